@@ -6,5 +6,6 @@ toolchain go1.23.4
 
 require (
 	github.com/gorilla/mux v1.8.1
+	github.com/gorilla/websocket v1.5.3
 	golang.org/x/time v0.12.0
 )
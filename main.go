@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -12,14 +13,134 @@ import (
 
 	"github.com/banking/ai-agents-banking/src/config"
 	"github.com/banking/ai-agents-banking/src/dao"
+	"github.com/banking/ai-agents-banking/src/fakeollama"
 	"github.com/banking/ai-agents-banking/src/handlers"
 	"github.com/banking/ai-agents-banking/src/services"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "scenarios" {
+		os.Exit(runScenarios(os.Args[2:]))
+	}
+
 	// Initialize configuration
 	cfg := config.New()
 
+	if cfg.FakeLLM != "" {
+		url, err := startFakeLLM(cfg.FakeLLM)
+		if err != nil {
+			log.Fatalf("Cannot start the fake LLM: %v", err)
+		}
+		cfg.LlamaURL = url
+	}
+
+	a, err := newApp(cfg)
+	if err != nil {
+		log.Fatalf("Cannot start: %v", err)
+	}
+
+	// Start cleanup routines
+	go a.sessionService.StartCleanupRoutine()
+	go a.pendingActions.StartCleanupRoutine()
+	if cfg.IntentReload > 0 {
+		go a.intentCatalog.StartWatching(cfg.IntentReload)
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = cfg.Port
+	}
+
+	// Ensure port has colon prefix
+	if !strings.HasPrefix(port, ":") {
+		port = ":" + port
+	}
+
+	// Create HTTP server with timeouts
+	srv := &http.Server{
+		Addr:         port,
+		Handler:      a.router,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+
+	log.Printf("🏦 Banking Agents Server starting on port %s", port)
+	log.Printf("🔧 Configuration:")
+	log.Printf("   Environment: %s", cfg.Environment)
+	log.Printf("   LLM: %s %s at %s", cfg.LLMProvider, cfg.LLMModel, cfg.LlamaURL)
+	if cfg.LLMFallbackModel != "" {
+		log.Printf("   LLM fallback: %s", cfg.LLMFallbackModel)
+	}
+	if cfg.LLMCassettes != "" {
+		log.Printf("   LLM cassettes: %s in %s", cfg.LLMCassettes, cfg.LLMCassetteDir)
+	}
+	log.Printf("   Prompts: %s, version %s", cfg.PromptDir, a.promptStore.Info().Label())
+	intents := a.intentCatalog.Info()
+	log.Printf("   Intents: %s, version %s@%s", intents.Path, intents.Version, intents.Hash)
+	log.Printf("   Log Level: %s", cfg.LogLevel)
+	log.Printf("")
+	log.Printf("📋 Available API endpoints:")
+	log.Printf("   POST   /auth - Authentication")
+	log.Printf("   GET    /health - Health check")
+	if cfg.Environment == "development" {
+		log.Printf("   GET    /routes - List all routes (dev only)")
+	}
+	log.Printf("")
+	log.Printf("   API v1 (Protected routes):")
+	log.Printf("   POST   /api/v1/chat - Chat with banking assistant")
+	log.Printf("   POST   /api/v1/chat/stream - Start streaming chat")
+	log.Printf("   GET    /api/v1/chat/stream/{streamId} - Follow streaming chat (SSE)")
+	log.Printf("   DELETE /api/v1/chat/stream/{streamId} - Cancel streaming chat")
+	log.Printf("   POST   /api/v1/chat/regenerate - Regenerate the last reply")
+	log.Printf("   POST   /api/v1/chat/edit - Edit the last message and answer again")
+	log.Printf("   GET    /api/v1/chat/poll/{sessionId} - Poll streaming session")
+	log.Printf("   GET    /api/v1/chat/ws - Chat over WebSocket")
+	log.Printf("   GET    /api/v1/chat/confirmations - Get pending confirmation")
+	log.Printf("   POST   /api/v1/chat/confirmations/{confirmationId} - Confirm pending action")
+	log.Printf("   DELETE /api/v1/chat/confirmations/{confirmationId} - Cancel pending action")
+	log.Printf("   GET    /api/v1/agents - List available agents")
+	log.Printf("   GET    /api/v1/agents/routing - Explain how a message is routed")
+	log.Printf("   GET    /api/v1/agents/{agentName} - Get agent details")
+	log.Printf("   GET    /api/v1/prompts - Describe the prompt templates in use")
+	log.Printf("   GET    /api/v1/intents - Describe the intent catalog in use")
+	log.Printf("   GET    /api/v1/conversation/history/{sessionId} - Get conversation history")
+	log.Printf("   DELETE /api/v1/conversation/clear/{sessionId} - Clear conversation")
+	log.Printf("")
+	log.Printf("   Banking API:")
+	log.Printf("   GET    /api/v1/banking/accounts - List accounts")
+	log.Printf("   GET    /api/v1/banking/accounts/{accountId}/balance - Get balance")
+	log.Printf("   GET    /api/v1/banking/transfers - List transfers")
+	log.Printf("   POST   /api/v1/banking/transfers - Create transfer")
+	log.Printf("   GET    /api/v1/banking/payees - List payees")
+	log.Printf("   POST   /api/v1/banking/payees - Create payee")
+	log.Printf("   GET    /api/v1/banking/loans/products - List loan products")
+	log.Printf("   POST   /api/v1/banking/loans/applications - Apply for loan")
+	if cfg.OpsToken != "" {
+		log.Printf("")
+		log.Printf("   Operations (X-Ops-Token):")
+		log.Printf("   POST   /ops/prompts/reload - Reload the prompt templates")
+	}
+	log.Printf("")
+	log.Printf("🚀 Server is ready!")
+
+	log.Fatal(srv.ListenAndServe())
+}
+
+// app is the wired server: its router and the parts that live beyond a request
+type app struct {
+	router         *mux.Router
+	promptStore    *services.PromptStore
+	intentCatalog  *services.IntentCatalog
+	sessionService *services.SessionService
+	pendingActions *services.PendingActionService
+	accountDAO     *dao.AccountDAO
+	payeeDAO       *dao.PayeeDAO
+	transferDAO    *dao.TransferDAO
+}
+
+// newApp wires the DAOs, services, handlers and routes for cfg
+func newApp(cfg *config.Config) (*app, error) {
 	// Initialize DAOs
 	sessionDAO := dao.NewSessionDAO()
 	accountDAO := dao.NewAccountDAO()
@@ -29,25 +150,67 @@ func main() {
 
 	// Initialize Services
 	sessionService := services.NewSessionService(sessionDAO)
-	intentService := services.NewIntentRecognitionService()
 	conversationService := services.NewConversationService()
-	llamaService := services.NewLlamaService(cfg.LlamaURL)
-	agentService := services.NewAgentService(accountDAO, payeeDAO, transferDAO, loanDAO)
-	toolRegistry := services.NewToolRegistry(15 * time.Minute)
+	primaryLLM, err := services.NewLLMProvider(cfg.LLMProvider, cfg.LlamaURL, cfg.LLMModel, cfg.LLMAPIKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM configuration: %v", err)
+	}
+	var fallbackLLM services.LLMProvider
+	if cfg.LLMFallbackModel != "" {
+		fallbackLLM, err = services.NewLLMProvider(cfg.LLMProvider, cfg.LlamaURL, cfg.LLMFallbackModel, cfg.LLMAPIKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback LLM configuration: %v", err)
+		}
+	}
+	llmProvider := services.NewResilientProvider(primaryLLM, fallbackLLM, cfg.LLMRetries, cfg.LLMBreakerFailures, cfg.LLMBreakerCooldown)
+	var replyProvider services.LLMProvider = llmProvider
+	if cfg.LLMCassettes != "" {
+		replyProvider, err = services.NewCassetteProvider(llmProvider, cfg.LLMCassettes, cfg.LLMCassetteDir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LLM cassette configuration: %v", err)
+		}
+	}
+	promptAssembler := services.NewPromptAssembler(cfg.PromptTokenBudget)
+	promptStore := services.NewPromptStore(cfg.PromptDir)
+	llamaService := services.NewLlamaService(replyProvider, promptStore, promptAssembler, cfg.StreamRate)
+	agentService := services.NewAgentService(accountDAO, payeeDAO, transferDAO, loanDAO, cfg.RoutingMargin)
+	var agentNames []string
+	for name := range agentService.GetAllAgents() {
+		agentNames = append(agentNames, name)
+	}
+	intentCatalog, err := services.NewIntentCatalog(cfg.IntentCatalog, agentNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid intent catalog: %v", err)
+	}
+	agentService.UseIntentCatalog(intentCatalog)
+	toolRegistry := services.NewToolRegistry(15*time.Minute, cfg.ToolTimeout, cfg.ToolTimeouts)
+	dialogueState := services.NewDialogueStateService()
+	pendingActions := services.NewPendingActionService(cfg.ConfirmationExpiry, "fund_transfer", "add_payee", "create_fd", "create_rd")
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(sessionService)
-	chatHandler := handlers.NewChatHandler(
-		sessionService,
+	chatOrchestrator := services.NewChatOrchestrator(
 		agentService,
 		conversationService,
-		intentService,
+		intentCatalog,
 		llamaService,
+		promptAssembler,
 		toolRegistry,
+		dialogueState,
+		pendingActions,
+		cfg.MaxToolRounds,
 	)
-	agentsHandler := handlers.NewAgentsHandler(agentService, sessionService)
-	healthHandler := handlers.NewHealthHandler(agentService, conversationService, sessionService)
+	chatHandler := handlers.NewChatHandler(
+		sessionService,
+		conversationService,
+		chatOrchestrator,
+	)
+	agentsHandler := handlers.NewAgentsHandler(agentService, sessionService, intentCatalog)
+	healthHandler := handlers.NewHealthHandler(agentService, conversationService, sessionService, llmProvider)
 	conversationHandler := handlers.NewConversationHandler(conversationService, sessionService)
+	confirmationHandler := handlers.NewConfirmationHandler(chatOrchestrator)
+	promptsHandler := handlers.NewPromptsHandler(promptStore)
+	intentsHandler := handlers.NewIntentsHandler(intentCatalog)
 
 	// Register banking tools
 	registerBankingTools(toolRegistry, agentService)
@@ -74,6 +237,13 @@ func main() {
 	r.HandleFunc("/auth", authHandler.ServeHTTP).Methods("POST", "OPTIONS")
 	r.HandleFunc("/health", healthHandler.ServeHTTP).Methods("GET", "OPTIONS")
 
+	// Operational routes answer to the operator token, never to customer sessions
+	if cfg.OpsToken != "" {
+		ops := r.PathPrefix("/ops").Subrouter()
+		ops.Use(middleware.NewOpsMiddleware(cfg.OpsToken).MiddlewareFunc)
+		ops.HandleFunc("/prompts/reload", promptsHandler.Reload).Methods("POST")
+	}
+
 	// API v1 subrouter
 	api := r.PathPrefix("/api/v1").Subrouter()
 	api.Use(authMiddleware.MiddlewareFunc) // All API v1 routes require authentication
@@ -81,13 +251,28 @@ func main() {
 	// Chat routes
 	chatRoutes := api.PathPrefix("/chat").Subrouter()
 	chatRoutes.HandleFunc("", chatHandler.ServeHTTP).Methods("GET", "POST", "OPTIONS")
-	//chatRoutes.HandleFunc("/stream", chatHandler.Stream).Methods("POST")
-	//chatRoutes.HandleFunc("/poll/{sessionId}", chatHandler.Poll).Methods("GET")
+	chatRoutes.HandleFunc("/stream", chatHandler.Stream).Methods("POST", "OPTIONS")
+	chatRoutes.HandleFunc("/stream/{streamId}", chatHandler.StreamEvents).Methods("GET")
+	chatRoutes.HandleFunc("/stream/{streamId}", chatHandler.CancelStream).Methods("DELETE")
+	chatRoutes.HandleFunc("/regenerate", chatHandler.Regenerate).Methods("POST")
+	chatRoutes.HandleFunc("/edit", chatHandler.Edit).Methods("POST")
+	chatRoutes.HandleFunc("/poll/{sessionId}", chatHandler.Poll).Methods("GET")
+	chatRoutes.HandleFunc("/ws", chatHandler.ServeWS).Methods("GET")
+	chatRoutes.HandleFunc("/confirmations", confirmationHandler.GetPending).Methods("GET")
+	chatRoutes.HandleFunc("/confirmations/{confirmationId}", confirmationHandler.Confirm).Methods("POST")
+	chatRoutes.HandleFunc("/confirmations/{confirmationId}", confirmationHandler.Cancel).Methods("DELETE")
 
 	// Agent routes
 	api.HandleFunc("/agents", agentsHandler.ServeHTTP).Methods("GET")
+	api.HandleFunc("/agents/routing", agentsHandler.Routing).Methods("GET")
 	api.HandleFunc("/agents/{agentName}", agentsHandler.GetAgentDetails).Methods("GET")
 
+	// Prompt routes
+	api.HandleFunc("/prompts", promptsHandler.ServeHTTP).Methods("GET")
+
+	// Intent routes
+	api.HandleFunc("/intents", intentsHandler.ServeHTTP).Methods("GET")
+
 	// Conversation routes
 	conversationRoutes := api.PathPrefix("/conversation").Subrouter()
 	conversationRoutes.HandleFunc("/history/{sessionId}", conversationHandler.ServeHTTP).Methods("GET")
@@ -134,63 +319,38 @@ func main() {
 		r.HandleFunc("/routes", listRoutesHandler).Methods("GET")
 	}
 
-	// Start cleanup routine
-	go sessionService.StartCleanupRoutine()
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = cfg.Port
-	}
+	return &app{
+		router:         r,
+		promptStore:    promptStore,
+		intentCatalog:  intentCatalog,
+		sessionService: sessionService,
+		pendingActions: pendingActions,
+		accountDAO:     accountDAO,
+		payeeDAO:       payeeDAO,
+		transferDAO:    transferDAO,
+	}, nil
+}
 
-	// Ensure port has colon prefix
-	if !strings.HasPrefix(port, ":") {
-		port = ":" + port
+// startFakeLLM serves the LLM from the built-in fake Ollama on a free local port, for
+// running without a model. script is "default" or the path of a script file.
+func startFakeLLM(script string) (string, error) {
+	fakeScript := fakeollama.DefaultScript()
+	if script != "default" {
+		var err error
+		if fakeScript, err = fakeollama.LoadScript(script); err != nil {
+			return "", err
+		}
 	}
-
-	// Create HTTP server with timeouts
-	srv := &http.Server{
-		Addr:         port,
-		Handler:      r,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  120 * time.Second,
+	server, err := fakeollama.New(fakeScript)
+	if err != nil {
+		return "", err
 	}
-
-	log.Printf("🏦 Banking Agents Server starting on port %s", port)
-	log.Printf("🔧 Configuration:")
-	log.Printf("   Environment: %s", cfg.Environment)
-	log.Printf("   LLaMA URL: %s", cfg.LlamaURL)
-	log.Printf("   Log Level: %s", cfg.LogLevel)
-	log.Printf("")
-	log.Printf("📋 Available API endpoints:")
-	log.Printf("   POST   /auth - Authentication")
-	log.Printf("   GET    /health - Health check")
-	if cfg.Environment == "development" {
-		log.Printf("   GET    /routes - List all routes (dev only)")
+	url, err := server.Listen("127.0.0.1:0")
+	if err != nil {
+		return "", err
 	}
-	log.Printf("")
-	log.Printf("   API v1 (Protected routes):")
-	log.Printf("   POST   /api/v1/chat - Chat with banking assistant")
-	log.Printf("   POST   /api/v1/chat/stream - Start streaming chat")
-	log.Printf("   GET    /api/v1/chat/poll/{sessionId} - Poll streaming session")
-	log.Printf("   GET    /api/v1/agents - List available agents")
-	log.Printf("   GET    /api/v1/agents/{agentName} - Get agent details")
-	log.Printf("   GET    /api/v1/conversation/history/{sessionId} - Get conversation history")
-	log.Printf("   DELETE /api/v1/conversation/clear/{sessionId} - Clear conversation")
-	log.Printf("")
-	log.Printf("   Banking API:")
-	log.Printf("   GET    /api/v1/banking/accounts - List accounts")
-	log.Printf("   GET    /api/v1/banking/accounts/{accountId}/balance - Get balance")
-	log.Printf("   GET    /api/v1/banking/transfers - List transfers")
-	log.Printf("   POST   /api/v1/banking/transfers - Create transfer")
-	log.Printf("   GET    /api/v1/banking/payees - List payees")
-	log.Printf("   POST   /api/v1/banking/payees - Create payee")
-	log.Printf("   GET    /api/v1/banking/loans/products - List loan products")
-	log.Printf("   POST   /api/v1/banking/loans/applications - Apply for loan")
-	log.Printf("")
-	log.Printf("🚀 Server is ready!")
-
-	log.Fatal(srv.ListenAndServe())
+	log.Printf("🧪 Fake LLM (%s) listening at %s", script, url)
+	return url, nil
 }
 
 func registerBankingTools(registry *services.ToolRegistry, agentService *services.AgentService) {
@@ -239,7 +399,6 @@ func registerBankingTools(registry *services.ToolRegistry, agentService *service
 	// Register weather tool
 	registry.RegisterTool(&services.WeatherTool{
 		CacheTTL: 15 * time.Minute,
-		Cache:    make(map[string]interface{}),
 	})
 }
 
@@ -252,9 +411,21 @@ func listRoutesHandler(w http.ResponseWriter, r *http.Request) {
     {"method": "GET", "path": "/routes", "description": "List all routes (dev only)", "protected": false},
     {"method": "POST", "path": "/api/v1/chat", "description": "Chat with banking assistant", "protected": true},
     {"method": "POST", "path": "/api/v1/chat/stream", "description": "Start streaming chat", "protected": true},
+    {"method": "GET", "path": "/api/v1/chat/stream/{streamId}", "description": "Follow streaming chat (SSE)", "protected": true},
+    {"method": "DELETE", "path": "/api/v1/chat/stream/{streamId}", "description": "Cancel streaming chat", "protected": true},
+    {"method": "POST", "path": "/api/v1/chat/regenerate", "description": "Regenerate the last reply", "protected": true},
+    {"method": "POST", "path": "/api/v1/chat/edit", "description": "Edit the last message and answer again", "protected": true},
     {"method": "GET", "path": "/api/v1/chat/poll/{sessionId}", "description": "Poll streaming session", "protected": true},
+    {"method": "GET", "path": "/api/v1/chat/ws", "description": "Chat over WebSocket", "protected": true},
+    {"method": "GET", "path": "/api/v1/chat/confirmations", "description": "Get pending confirmation", "protected": true},
+    {"method": "POST", "path": "/api/v1/chat/confirmations/{confirmationId}", "description": "Confirm pending action", "protected": true},
+    {"method": "DELETE", "path": "/api/v1/chat/confirmations/{confirmationId}", "description": "Cancel pending action", "protected": true},
     {"method": "GET", "path": "/api/v1/agents", "description": "List available agents", "protected": true},
+    {"method": "GET", "path": "/api/v1/agents/routing", "description": "Explain how a message is routed", "protected": true},
     {"method": "GET", "path": "/api/v1/agents/{agentName}", "description": "Get agent details", "protected": true},
+    {"method": "GET", "path": "/api/v1/prompts", "description": "Describe the prompt templates in use", "protected": true},
+    {"method": "POST", "path": "/ops/prompts/reload", "description": "Reload the prompt templates (X-Ops-Token; only when OPS_TOKEN is set)", "protected": true},
+    {"method": "GET", "path": "/api/v1/intents", "description": "Describe the intent catalog in use", "protected": true},
     {"method": "GET", "path": "/api/v1/conversation/history/{sessionId}", "description": "Get conversation history", "protected": true},
     {"method": "DELETE", "path": "/api/v1/conversation/clear/{sessionId}", "description": "Clear conversation", "protected": true},
     {"method": "GET", "path": "/api/v1/banking/accounts", "description": "List accounts", "protected": true},
@@ -275,7 +446,7 @@ func listRoutesHandler(w http.ResponseWriter, r *http.Request) {
     {"method": "POST", "path": "/api/v1/banking/loans/eligibility", "description": "Check loan eligibility", "protected": true},
     {"method": "POST", "path": "/api/v1/banking/loans/calculate-emi", "description": "Calculate EMI", "protected": true}
   ],
-  "total": 26,
+  "total": 39,
   "server_info": {
     "framework": "Gorilla Mux",
     "version": "1.0.0",
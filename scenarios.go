@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/banking/ai-agents-banking/src/config"
+	"github.com/banking/ai-agents-banking/src/fakeollama"
+	"github.com/banking/ai-agents-banking/src/models"
+	"github.com/banking/ai-agents-banking/src/scenario"
+	"github.com/banking/ai-agents-banking/src/services"
+)
+
+// scenarioState is the banking data of a customer as scenario expectations name it
+type scenarioState struct {
+	Accounts  map[string]models.Account `json:"accounts"` // By account ID
+	Payees    map[string]models.Payee   `json:"payees"`   // By name
+	Transfers []models.Transfer         `json:"transfers"`
+}
+
+// runScenarios runs the conversation scenarios in the given files and directories
+// and returns the exit code: 0 when all of them pass
+//
+//	go run . scenarios [-v] [-json report.json] [paths...]
+func runScenarios(args []string) int {
+	flags := flag.NewFlagSet("scenarios", flag.ContinueOnError)
+	verbose := flags.Bool("v", false, "show the server logs")
+	jsonReport := flags.String("json", "", "also write the report as JSON to this file")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"testdata/scenarios"}
+	}
+
+	scenarios, err := scenario.Load(paths...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot load scenarios: %v\n", err)
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	report := scenario.NewRunner(newScenarioStack).Run(scenarios)
+	report.Write(os.Stdout)
+
+	if *jsonReport != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(*jsonReport, data, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot write %s: %v\n", *jsonReport, err)
+			return 2
+		}
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// newScenarioStack wires the whole server, with fresh banking data, against a fake
+// Ollama answering from script. Recorded cassettes and fallback models are left out so
+// that only the script decides what the model says.
+func newScenarioStack(script *fakeollama.Script) (*scenario.Stack, error) {
+	return newConfiguredScenarioStack(script, nil)
+}
+
+// newConfiguredScenarioStack is newScenarioStack with configure, when given, applied
+// last to the server's settings
+func newConfiguredScenarioStack(script *fakeollama.Script, configure func(*config.Config)) (*scenario.Stack, error) {
+	fake, err := fakeollama.New(script)
+	if err != nil {
+		return nil, err
+	}
+	url, err := fake.Listen("127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.New()
+	cfg.LlamaURL = url
+	if cfg.LLMProvider != services.ProviderOllamaChat {
+		cfg.LLMProvider = services.ProviderOllamaGenerate
+	}
+	cfg.LLMAPIKey = ""
+	cfg.LLMFallbackModel = ""
+	cfg.LLMCassettes = ""
+	if configure != nil {
+		configure(cfg)
+	}
+
+	a, err := newApp(cfg)
+	if err != nil {
+		fake.Close()
+		return nil, err
+	}
+
+	return &scenario.Stack{
+		Handler: a.router,
+		State: func(userID string) interface{} {
+			state := scenarioState{Accounts: map[string]models.Account{}, Payees: map[string]models.Payee{}}
+			accounts, _ := a.accountDAO.GetUserAccounts(userID)
+			for _, account := range accounts {
+				state.Accounts[account.AccountID] = account
+			}
+			payees, _ := a.payeeDAO.GetUserPayees(userID)
+			for _, payee := range payees {
+				state.Payees[payee.Name] = payee
+			}
+			state.Transfers, _ = a.transferDAO.GetUserTransfers(userID)
+			return state
+		},
+		Close: func() { fake.Close() },
+	}, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/banking/ai-agents-banking/src/config"
+	"github.com/banking/ai-agents-banking/src/fakeollama"
+	"github.com/banking/ai-agents-banking/src/scenario"
+	"github.com/banking/ai-agents-banking/src/services"
+)
+
+// TestScenarios runs every conversation in testdata/scenarios against the fake Ollama
+func TestScenarios(t *testing.T) {
+	runScenarioTests(t, newScenarioStack)
+}
+
+// TestScenariosReplay records the scenarios' model answers to cassettes, then runs them
+// again from the cassettes alone with no model reachable
+func TestScenariosReplay(t *testing.T) {
+	dir := t.TempDir()
+	runScenarioTests(t, func(script *fakeollama.Script) (*scenario.Stack, error) {
+		return newConfiguredScenarioStack(script, func(cfg *config.Config) {
+			cfg.LLMCassettes, cfg.LLMCassetteDir = services.CassetteRecord, dir
+		})
+	})
+
+	recorded, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(recorded) == 0 {
+		t.Fatal("no cassettes were recorded")
+	}
+
+	runScenarioTests(t, func(script *fakeollama.Script) (*scenario.Stack, error) {
+		return newConfiguredScenarioStack(script, func(cfg *config.Config) {
+			cfg.LlamaURL = "http://127.0.0.1:1"
+			cfg.LLMCassettes, cfg.LLMCassetteDir = services.CassetteReplay, dir
+		})
+	})
+}
+
+func runScenarioTests(t *testing.T, newStack scenario.StackFunc) {
+	t.Helper()
+	scenarios, err := scenario.Load("testdata/scenarios")
+	if err != nil {
+		t.Fatalf("Cannot load scenarios: %v", err)
+	}
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+		t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	}
+
+	report := scenario.NewRunner(newStack).Run(scenarios)
+	for _, result := range report.Results {
+		t.Run(result.Scenario, func(t *testing.T) {
+			for _, failure := range result.Failures {
+				t.Errorf("turn %d %q: %s: expected %s, got %s", failure.Turn, failure.Say, failure.Field, failure.Expected, failure.Actual)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package agents
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -20,27 +21,22 @@ func NewAccountBalanceAgent(accountDAO *dao.AccountDAO) *AccountBalanceAgent {
 			Description: "Provides account balance and account information",
 			Tools:       []string{"check_balance", "download_statement", "view_transactions", "set_alerts"},
 			Confidence:  0.95,
+			Routing: RoutingProfile{
+				Intents:  []string{"check_balance", "view_balance", "account_balance", "balance_inquiry"},
+				Keywords: map[string]float64{"balance": 1.0, "statement": 0.8, "account": 0.3, "check": 0.3, "show": 0.2},
+				Topic:    "check your balance",
+			},
 		},
 		accountDAO: accountDAO,
 	}
 }
 
 func (a *AccountBalanceAgent) CanHandle(intent string, message string) bool {
-	balanceIntents := []string{"check_balance", "view_balance", "account_balance", "balance_inquiry"}
-	for _, bi := range balanceIntents {
-		if intent == bi {
-			return true
-		}
-	}
-
-	balanceKeywords := []string{"balance", "account", "check", "show", "statement"}
-	lowerMsg := strings.ToLower(message)
-	for _, keyword := range balanceKeywords {
-		if strings.Contains(lowerMsg, keyword) {
-			return true
-		}
+	if a.Routing.OwnsIntent(intent) {
+		return true
 	}
-	return false
+	score, _ := a.Routing.MatchKeywords(message)
+	return score > 0
 }
 
 func (a *AccountBalanceAgent) GetRequiredParameters() []string {
@@ -51,8 +47,8 @@ func (a *AccountBalanceAgent) ValidateParameters(params map[string]interface{})
 	return []string{} // Always valid
 }
 
-func (a *AccountBalanceAgent) Process(ctx *models.AgentContext) *models.AgentResponse {
-	accounts, err := a.accountDAO.GetUserAccounts(ctx.UserID)
+func (a *AccountBalanceAgent) Process(ctx context.Context, agentCtx *models.AgentContext) *models.AgentResponse {
+	accounts, err := a.accountDAO.GetUserAccounts(agentCtx.UserID)
 	if err != nil {
 		return &models.AgentResponse{
 			Message:   "Failed to retrieve account information",
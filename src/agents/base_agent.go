@@ -1,17 +1,65 @@
 package agents
 
-import "github.com/banking/ai-agents-banking/src/models"
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/banking/ai-agents-banking/src/models"
+)
 
 type BankingAgent interface {
 	GetName() string
 	GetDescription() string
 	CanHandle(intent string, message string) bool
-	Process(ctx *models.AgentContext) *models.AgentResponse
+	Process(ctx context.Context, agentCtx *models.AgentContext) *models.AgentResponse
 	ValidateParameters(params map[string]interface{}) []string
 	GetRequiredParameters() []string
 	GetHelp() string
 	GetTools() []string
 	GetConfidence() float64
+	GetRoutingProfile() RoutingProfile
+}
+
+// RoutingProfile tells the router which requests an agent is meant for
+type RoutingProfile struct {
+	Intents  []string           // Recognised intents the agent owns
+	Keywords map[string]float64 // Words and phrases that point to the agent, by weight
+	Topic    string             // Short phrase used when the user is asked to choose, e.g. "transfer money"
+}
+
+// OwnsIntent reports whether the recognised intent belongs to the agent
+func (p RoutingProfile) OwnsIntent(intent string) bool {
+	for _, owned := range p.Intents {
+		if intent == owned {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchKeywords returns the summed weight of the keywords found in message, and the
+// keywords themselves in sorted order. Keywords match at the start of a word, so
+// "transfer" matches "transfers" but "fd" does not match "pdf".
+func (p RoutingProfile) MatchKeywords(message string) (float64, []string) {
+	text := " " + strings.Join(strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+
+	var matched []string
+	for keyword := range p.Keywords {
+		if strings.Contains(text, " "+keyword) {
+			matched = append(matched, keyword)
+		}
+	}
+	// Summed in a fixed order so equal messages always score exactly the same
+	sort.Strings(matched)
+	var score float64
+	for _, keyword := range matched {
+		score += p.Keywords[keyword]
+	}
+	return score, matched
 }
 
 // BaseAgent provides common functionality for all agents
@@ -20,6 +68,7 @@ type BaseAgent struct {
 	Description string
 	Tools       []string
 	Confidence  float64
+	Routing     RoutingProfile
 }
 
 func (a *BaseAgent) GetName() string {
@@ -38,6 +87,10 @@ func (a *BaseAgent) GetConfidence() float64 {
 	return a.Confidence
 }
 
+func (a *BaseAgent) GetRoutingProfile() RoutingProfile {
+	return a.Routing
+}
+
 func (a *BaseAgent) ValidateParameters(params map[string]interface{}) []string {
 	return []string{}
 }
@@ -49,3 +102,11 @@ func (a *BaseAgent) GetRequiredParameters() []string {
 func (a *BaseAgent) GetHelp() string {
 	return "No help available for this agent."
 }
+
+// lastFour returns the last four characters of an account number for masked display
+func lastFour(accountNumber string) string {
+	if len(accountNumber) <= 4 {
+		return accountNumber
+	}
+	return accountNumber[len(accountNumber)-4:]
+}
@@ -0,0 +1,172 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+type DepositAgent struct {
+	*BaseAgent
+}
+
+func NewDepositAgent() *DepositAgent {
+	return &DepositAgent{
+		BaseAgent: &BaseAgent{
+			Name:        "DepositAgent",
+			Description: "Opens fixed and recurring deposits and provides deposit interest rates",
+			Tools:       []string{"create_fd", "create_rd", "get_interest_rates"},
+			Confidence:  0.9,
+			Routing: RoutingProfile{
+				Intents:  []string{"create_fd", "create_rd", "fixed_deposit", "recurring_deposit", "interest_rates"},
+				Keywords: map[string]float64{"fixed deposit": 1.5, "recurring deposit": 1.5, "fd": 1.0, "rd": 1.0, "deposit rate": 1.0},
+				Topic:    "open a deposit",
+			},
+		},
+	}
+}
+
+func (a *DepositAgent) CanHandle(intent string, message string) bool {
+	if a.Routing.OwnsIntent(intent) {
+		return true
+	}
+	score, _ := a.Routing.MatchKeywords(message)
+	return score > 0
+}
+
+func (a *DepositAgent) GetRequiredParameters() []string {
+	return []string{"amount", "tenure"}
+}
+
+func (a *DepositAgent) ValidateParameters(params map[string]interface{}) []string {
+	required := a.GetRequiredParameters()
+	var missing []string
+
+	for _, param := range required {
+		if _, exists := params[param]; !exists {
+			missing = append(missing, param)
+		}
+	}
+
+	// Validate amount if present
+	if amountStr, exists := params["amount"]; exists {
+		if amount, err := strconv.ParseFloat(fmt.Sprintf("%v", amountStr), 64); err != nil || amount <= 0 {
+			missing = append(missing, "valid_amount")
+		}
+	}
+
+	// Validate tenure if present
+	if tenureStr, exists := params["tenure"]; exists {
+		if tenure, err := strconv.Atoi(fmt.Sprintf("%v", tenureStr)); err != nil || tenure <= 0 {
+			missing = append(missing, "valid_tenure")
+		}
+	}
+
+	return missing
+}
+
+func (a *DepositAgent) Process(ctx context.Context, agentCtx *models.AgentContext) *models.AgentResponse {
+	lowerMsg := strings.ToLower(agentCtx.Message)
+	if agentCtx.Intent == "interest_rates" || strings.Contains(lowerMsg, "rate") {
+		return a.handleInterestRates(agentCtx)
+	}
+
+	missing := a.ValidateParameters(agentCtx.Parameters)
+	if len(missing) > 0 {
+		return &models.AgentResponse{
+			Message:           a.getQuestionForMissing(missing[0]),
+			AgentName:         a.Name,
+			RequiresInput:     true,
+			MissingParameters: missing,
+		}
+	}
+
+	amount, _ := strconv.ParseFloat(fmt.Sprintf("%v", agentCtx.Parameters["amount"]), 64)
+	tenure, _ := strconv.Atoi(fmt.Sprintf("%v", agentCtx.Parameters["tenure"]))
+
+	// Tool APIs are expressed in months
+	tenureUnit := fmt.Sprintf("%v", agentCtx.Parameters["tenure_unit"])
+	switch {
+	case strings.HasPrefix(tenureUnit, "y"):
+		tenure *= 12
+	case strings.HasPrefix(tenureUnit, "d"):
+		tenure /= 30
+	}
+
+	toolName := "create_fd"
+	productName := "fixed deposit"
+	if agentCtx.Intent == "create_rd" || strings.Contains(lowerMsg, "recurring") || strings.Contains(" "+lowerMsg, " rd") {
+		toolName = "create_rd"
+		productName = "recurring deposit"
+	}
+
+	return &models.AgentResponse{
+		Message:      fmt.Sprintf("Opening a %s of ₹%.2f for %d months.", productName, amount, tenure),
+		AgentName:    a.Name,
+		Actions:      a.Tools,
+		RequiresTool: true,
+		ToolName:     toolName,
+		ToolParams: map[string]interface{}{
+			"amount":      amount,
+			"tenure":      tenure,
+			"tenure_unit": "months",
+		},
+	}
+}
+
+func (a *DepositAgent) handleInterestRates(agentCtx *models.AgentContext) *models.AgentResponse {
+	productType := "all"
+	lowerMsg := " " + strings.ToLower(agentCtx.Message)
+	if strings.Contains(lowerMsg, "fixed") || strings.Contains(lowerMsg, " fd") {
+		productType = "fd"
+	} else if strings.Contains(lowerMsg, "recurring") || strings.Contains(lowerMsg, " rd") {
+		productType = "rd"
+	}
+
+	return &models.AgentResponse{
+		Message:      "Fetching the latest interest rates.",
+		AgentName:    a.Name,
+		Actions:      a.Tools,
+		RequiresTool: true,
+		ToolName:     "get_interest_rates",
+		ToolParams:   map[string]interface{}{"product_type": productType},
+	}
+}
+
+func (a *DepositAgent) getQuestionForMissing(param string) string {
+	switch param {
+	case "amount":
+		return "💰 How much would you like to deposit?"
+	case "tenure":
+		return "⏱️ For how long would you like to keep the deposit? (e.g., 12 months)"
+	case "valid_amount":
+		return "❌ Please enter a valid amount greater than 0"
+	case "valid_tenure":
+		return "❌ Please enter a valid tenure in months"
+	default:
+		return fmt.Sprintf("Please provide the %s for the deposit", param)
+	}
+}
+
+func (a *DepositAgent) GetHelp() string {
+	return `🏦 **Deposit Agent Help**
+
+I can help you grow your savings:
+
+**Available Services:**
+• Open a fixed deposit (FD)
+• Open a recurring deposit (RD)
+• Current deposit interest rates
+
+**What I need:**
+• Deposit amount
+• Tenure (months or years)
+
+**Example commands:**
+• "Create a fixed deposit of 50000 for 12 months"
+• "Open an RD of 2000 for 2 years"
+• "What are the FD interest rates?"`
+}
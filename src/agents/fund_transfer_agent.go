@@ -1,13 +1,14 @@
 package agents
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/banking/ai-agents-banking/src/dao"
 	"github.com/banking/ai-agents-banking/src/models"
+	"github.com/banking/ai-agents-banking/src/payees"
 	"github.com/banking/ai-agents-banking/src/utils"
 )
 
@@ -16,42 +17,44 @@ type FundTransferAgent struct {
 	accountDAO  *dao.AccountDAO
 	payeeDAO    *dao.PayeeDAO
 	transferDAO *dao.TransferDAO
+	recipients  *payees.Resolver
 }
 
-func NewFundTransferAgent(accountDAO *dao.AccountDAO, payeeDAO *dao.PayeeDAO, transferDAO *dao.TransferDAO) *FundTransferAgent {
+// NewFundTransferAgent creates the agent. recipients is the resolver transfers are
+// made through, so the payee the user confirms is the one that gets paid.
+func NewFundTransferAgent(accountDAO *dao.AccountDAO, payeeDAO *dao.PayeeDAO, transferDAO *dao.TransferDAO, recipients *payees.Resolver) *FundTransferAgent {
 	return &FundTransferAgent{
 		BaseAgent: &BaseAgent{
 			Name:        "FundTransferAgent",
 			Description: "Handles money transfers via UPI, IMPS, NEFT, and RTGS",
 			Tools:       []string{"transfer_money", "view_transaction_details", "download_receipt"},
 			Confidence:  0.9,
+			Routing: RoutingProfile{
+				Intents: []string{"fund_transfer", "send_money", "transfer_money", "pay_money", "upi_transfer"},
+				Keywords: map[string]float64{
+					"transfer": 1.0, "send": 0.8, "pay": 0.6, "money": 0.4,
+					"upi": 0.8, "imps": 0.8, "neft": 0.8, "rtgs": 0.8,
+				},
+				Topic: "transfer money",
+			},
 		},
 		accountDAO:  accountDAO,
 		payeeDAO:    payeeDAO,
 		transferDAO: transferDAO,
+		recipients:  recipients,
 	}
 }
 
 func (a *FundTransferAgent) CanHandle(intent string, message string) bool {
-	transferIntents := []string{"fund_transfer", "send_money", "transfer_money", "pay_money", "upi_transfer"}
-	for _, ti := range transferIntents {
-		if intent == ti {
-			return true
-		}
+	if a.Routing.OwnsIntent(intent) {
+		return true
 	}
-
-	transferKeywords := []string{"transfer", "send", "pay", "money", "upi", "imps", "neft", "rtgs"}
-	lowerMsg := strings.ToLower(message)
-	for _, keyword := range transferKeywords {
-		if strings.Contains(lowerMsg, keyword) {
-			return true
-		}
-	}
-	return false
+	score, _ := a.Routing.MatchKeywords(message)
+	return score > 0
 }
 
 func (a *FundTransferAgent) GetRequiredParameters() []string {
-	return []string{"amount", "method"}
+	return []string{"amount", "method", "recipient"}
 }
 
 func (a *FundTransferAgent) ValidateParameters(params map[string]interface{}) []string {
@@ -74,8 +77,18 @@ func (a *FundTransferAgent) ValidateParameters(params map[string]interface{}) []
 	return missing
 }
 
-func (a *FundTransferAgent) Process(ctx *models.AgentContext) *models.AgentResponse {
-	missing := a.ValidateParameters(ctx.Parameters)
+func (a *FundTransferAgent) Process(ctx context.Context, agentCtx *models.AgentContext) *models.AgentResponse {
+	// A UPI ID or account suffix stands in for a recipient the message did not name
+	if _, named := agentCtx.Parameters["recipient"]; !named && agentCtx.Parameters != nil {
+		for _, key := range []string{"upi_id", "account_suffix"} {
+			if value, found := agentCtx.Parameters[key]; found {
+				agentCtx.Parameters["recipient"] = value
+				break
+			}
+		}
+	}
+
+	missing := a.ValidateParameters(agentCtx.Parameters)
 	if len(missing) > 0 {
 		return &models.AgentResponse{
 			Message:           a.getQuestionForMissing(missing[0]),
@@ -85,11 +98,22 @@ func (a *FundTransferAgent) Process(ctx *models.AgentContext) *models.AgentRespo
 		}
 	}
 
-	amount, _ := strconv.ParseFloat(fmt.Sprintf("%v", ctx.Parameters["amount"]), 64)
-	method := fmt.Sprintf("%v", ctx.Parameters["method"])
+	amount, _ := strconv.ParseFloat(fmt.Sprintf("%v", agentCtx.Parameters["amount"]), 64)
+	method := fmt.Sprintf("%v", agentCtx.Parameters["method"])
+	recipient := fmt.Sprintf("%v", agentCtx.Parameters["recipient"])
+
+	// Saved payees are resolved before anything is confirmed, so the user sees who gets the money
+	payee, question := a.resolvePayee(agentCtx, recipient)
+	if question != nil {
+		return question
+	}
+	payeeID, to := "", recipient
+	if payee != nil {
+		payeeID, recipient, to = payee.ID, payee.Name, describePayee(payee)
+	}
 
-	// Check user's account balance
-	userAccount, err := a.accountDAO.GetUserAccount(ctx.UserID)
+	// Check the balance of the account the money would come from
+	userAccount, err := a.sourceAccount(agentCtx)
 	if err != nil {
 		return &models.AgentResponse{
 			Message:   "Failed to retrieve account information",
@@ -106,45 +130,102 @@ func (a *FundTransferAgent) Process(ctx *models.AgentContext) *models.AgentRespo
 		}
 	}
 
-	// Process transfer
-	transferID := fmt.Sprintf("TXN%d", time.Now().UnixNano())
 	fees := utils.CalculateTransferFees(method, amount)
+	toolParams := map[string]interface{}{
+		"amount":    amount,
+		"method":    method,
+		"recipient": recipient,
+	}
+	if payeeID != "" {
+		toolParams["payee_id"] = payeeID
+	}
 
-	transfer := models.TransferRequest{
-		TransferID: transferID,
-		Amount:     amount,
-		Method:     method,
-		Status:     "SUCCESS",
-		Timestamp:  time.Now(),
-		Reference:  fmt.Sprintf("REF%d", time.Now().UnixNano()),
-		Fees:       fees,
-	}
-
-	// Update account balance
-	a.accountDAO.UpdateAccountBalance(ctx.UserID, userAccount.AccountNumber, userAccount.Balance-amount-fees)
-
-	// Store transfer history
-	transferRecord := models.Transfer{
-		TransferID:    transfer.TransferID,
-		FromAccountID: userAccount.AccountID,
-		ToAccountID:   "", // Set appropriately if you have payee info
-		Amount:        transfer.Amount,
-		Method:        transfer.Method,
-		Status:        transfer.Status,
-		Timestamp:     transfer.Timestamp,
-		Reference:     transfer.Reference,
-		Fees:          transfer.Fees,
-		Description:   transfer.Description,
-	}
-	a.transferDAO.AddTransfer(ctx.UserID, transferRecord)
+	// Money only moves once the user has confirmed the exact transfer
+	if !agentCtx.Confirmed {
+		return &models.AgentResponse{
+			Message:              fmt.Sprintf("Transfer ₹%.2f to %s via %s (fees ₹%.2f) from account ****%s", amount, to, method, fees, lastFour(userAccount.AccountNumber)),
+			AgentName:            a.Name,
+			Data:                 map[string]interface{}{"amount": amount, "method": method, "fees": fees},
+			RequiresConfirmation: true,
+			ToolName:             "fund_transfer",
+			ToolParams:           toolParams,
+		}
+	}
 
+	// Never move money for a request that has already been abandoned
+	if err := ctx.Err(); err != nil {
+		return &models.AgentResponse{
+			Message:   "The transfer was cancelled before it was made. No money was moved.",
+			AgentName: a.Name,
+			Data:      map[string]interface{}{"error": err.Error()},
+		}
+	}
+
+	// The fund_transfer tool debits the account and records the transfer for this user
 	return &models.AgentResponse{
-		Message: fmt.Sprintf("✅ Transfer completed successfully!\n💰 Amount: ₹%.2f\n🏦 Method: %s\n📋 Reference: %s\n💳 Transaction ID: %s\n💵 Fees: ₹%.2f",
-			amount, method, transfer.Reference, transferID, fees),
-		Data:      transfer,
-		Actions:   a.Tools,
-		AgentName: a.Name,
+		Message: fmt.Sprintf("✅ Transfer submitted!\n💰 Amount: ₹%.2f\n👤 To: %s\n🏦 Method: %s\n💵 Fees: ₹%.2f",
+			amount, to, method, fees),
+		Actions:      a.Tools,
+		AgentName:    a.Name,
+		RequiresTool: true,
+		ToolName:     "fund_transfer",
+		ToolParams:   toolParams,
+	}
+}
+
+// resolvePayee finds the saved payee a recipient refers to. It returns a question
+// instead when several payees match, or when a name matches none and the user is
+// offered to add it. Own accounts, account numbers and UPI IDs of payees not saved
+// are paid as given, without a payee.
+func (a *FundTransferAgent) resolvePayee(agentCtx *models.AgentContext, recipient string) (*models.Payee, *models.AgentResponse) {
+	resolution := a.recipients.Resolve(agentCtx.UserID, recipient)
+	switch {
+	case resolution.Resolved():
+		return resolution.Payee, nil
+	case resolution.Ambiguous():
+		var choices strings.Builder
+		for _, match := range resolution.Matches {
+			choices.WriteString("\n• " + describePayee(&match.Payee))
+		}
+		return nil, &models.AgentResponse{
+			Message:           fmt.Sprintf("👥 Several of your payees match \"%s\":%s\nWhich one do you mean? Reply with the name or the last 4 digits of the account.", recipient, choices.String()),
+			AgentName:         a.Name,
+			Data:              map[string]interface{}{"candidates": resolution.Matches},
+			RequiresInput:     true,
+			MissingParameters: []string{"recipient"},
+		}
+	}
+
+	return nil, &models.AgentResponse{
+		Message:           fmt.Sprintf("👤 I couldn't find \"%s\" among your payees. Would you like to add %s as a payee? Reply 'yes' to add them, or tell me another payee.", recipient, recipient),
+		AgentName:         a.Name,
+		RequiresInput:     true,
+		MissingParameters: []string{"recipient"},
+		Handoff: &models.Handoff{
+			Agent:      "AddPayeeAgent",
+			Intent:     "add_payee",
+			Parameters: map[string]interface{}{"payee_name": recipient},
+		},
+	}
+}
+
+// describePayee names a payee with the end of its account number, or its UPI ID
+func describePayee(payee *models.Payee) string {
+	if payee.AccountNo != "" {
+		return fmt.Sprintf("%s (****%s)", payee.Name, lastFour(payee.AccountNo))
+	}
+	if payee.UPIId != "" {
+		return fmt.Sprintf("%s (%s)", payee.Name, payee.UPIId)
+	}
+	return payee.Name
+}
+
+// sourceAccount returns the account selected for the session, or the user's primary account
+func (a *FundTransferAgent) sourceAccount(agentCtx *models.AgentContext) (*models.Account, error) {
+	if agentCtx.AccountID != "" {
+		return a.accountDAO.GetAccountByID(agentCtx.UserID, agentCtx.AccountID)
 	}
+	return a.accountDAO.GetUserAccount(agentCtx.UserID)
 }
 
 func (a *FundTransferAgent) getQuestionForMissing(param string) string {
@@ -153,7 +234,7 @@ func (a *FundTransferAgent) getQuestionForMissing(param string) string {
 		return "💰 How much would you like to transfer?"
 	case "method":
 		return "🏦 Which transfer method would you prefer?\n1. UPI (Instant)\n2. IMPS (Instant)\n3. NEFT (Up to 2 hours)\n4. RTGS (Real-time for large amounts)"
-	case "payee", "to_account":
+	case "recipient", "payee", "to_account":
 		return "👤 Who would you like to transfer money to? (Payee name or account number)"
 	case "valid_amount":
 		return "❌ Please enter a valid amount greater than 0"
@@ -1,6 +1,7 @@
 package agents
 
 import (
+	"context"
 	"strings"
 
 	"github.com/banking/ai-agents-banking/src/models"
@@ -25,9 +26,9 @@ func (a *GeneralBankingAgent) CanHandle(intent string, message string) bool {
 	return true // Fallback agent handles everything
 }
 
-func (a *GeneralBankingAgent) Process(ctx *models.AgentContext) *models.AgentResponse {
+func (a *GeneralBankingAgent) Process(ctx context.Context, agentCtx *models.AgentContext) *models.AgentResponse {
 	// This agent provides general responses and guidance
-	response := a.generateGeneralResponse(ctx.Message)
+	response := a.generateGeneralResponse(agentCtx.Message)
 
 	return &models.AgentResponse{
 		Message:       response,
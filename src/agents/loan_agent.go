@@ -1,6 +1,7 @@
 package agents
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"strconv"
@@ -23,27 +24,25 @@ func NewLoanAgent(loanDAO *dao.LoanDAO) *LoanAgent {
 			Description: "Handles loan applications, eligibility checks, and loan information",
 			Tools:       []string{"apply_loan", "check_eligibility", "calculate_emi", "upload_documents", "check_status"},
 			Confidence:  0.9,
+			Routing: RoutingProfile{
+				Intents: []string{"loan_application", "apply_loan", "loan_eligibility", "loan_info", "personal_loan", "home_loan"},
+				Keywords: map[string]float64{
+					"loan": 1.0, "emi": 1.0, "eligibility": 0.8, "apply": 0.3, "interest": 0.3,
+					"personal loan": 0.5, "home loan": 0.5, "car loan": 0.5,
+				},
+				Topic: "explore loans",
+			},
 		},
 		loanDAO: loanDAO,
 	}
 }
 
 func (a *LoanAgent) CanHandle(intent string, message string) bool {
-	loanIntents := []string{"loan_application", "apply_loan", "loan_eligibility", "loan_info", "personal_loan", "home_loan"}
-	for _, li := range loanIntents {
-		if intent == li {
-			return true
-		}
-	}
-
-	loanKeywords := []string{"loan", "apply", "eligibility", "emi", "interest", "personal loan", "home loan", "car loan"}
-	lowerMsg := strings.ToLower(message)
-	for _, keyword := range loanKeywords {
-		if strings.Contains(lowerMsg, keyword) {
-			return true
-		}
+	if a.Routing.OwnsIntent(intent) {
+		return true
 	}
-	return false
+	score, _ := a.Routing.MatchKeywords(message)
+	return score > 0
 }
 
 func (a *LoanAgent) GetRequiredParameters() []string {
@@ -68,25 +67,25 @@ func (a *LoanAgent) ValidateParameters(params map[string]interface{}) []string {
 	return missing
 }
 
-func (a *LoanAgent) Process(ctx *models.AgentContext) *models.AgentResponse {
-	action := ctx.Parameters["action"]
+func (a *LoanAgent) Process(ctx context.Context, agentCtx *models.AgentContext) *models.AgentResponse {
+	action := agentCtx.Parameters["action"]
 	if action == nil {
 		action = "info" // Default to showing info
 	}
 
 	switch action {
 	case "apply":
-		return a.handleLoanApplication(ctx)
+		return a.handleLoanApplication(agentCtx)
 	case "eligibility":
-		return a.handleEligibilityCheck(ctx)
+		return a.handleEligibilityCheck(agentCtx)
 	case "calculate":
-		return a.handleEMICalculation(ctx)
+		return a.handleEMICalculation(agentCtx)
 	default:
-		return a.handleLoanInfo(ctx)
+		return a.handleLoanInfo(agentCtx)
 	}
 }
 
-func (a *LoanAgent) handleLoanInfo(ctx *models.AgentContext) *models.AgentResponse {
+func (a *LoanAgent) handleLoanInfo(agentCtx *models.AgentContext) *models.AgentResponse {
 	loanProducts, err := a.loanDAO.GetLoanProducts()
 	if err != nil {
 		return &models.AgentResponse{
@@ -115,8 +114,8 @@ func (a *LoanAgent) handleLoanInfo(ctx *models.AgentContext) *models.AgentRespon
 	}
 }
 
-func (a *LoanAgent) handleLoanApplication(ctx *models.AgentContext) *models.AgentResponse {
-	missing := a.ValidateParameters(ctx.Parameters)
+func (a *LoanAgent) handleLoanApplication(agentCtx *models.AgentContext) *models.AgentResponse {
+	missing := a.ValidateParameters(agentCtx.Parameters)
 	if len(missing) > 0 {
 		return &models.AgentResponse{
 			Message:           a.getQuestionForMissing(missing[0]),
@@ -126,8 +125,8 @@ func (a *LoanAgent) handleLoanApplication(ctx *models.AgentContext) *models.Agen
 		}
 	}
 
-	loanType := fmt.Sprintf("%v", ctx.Parameters["loan_type"])
-	amount, _ := strconv.ParseFloat(fmt.Sprintf("%v", ctx.Parameters["amount"]), 64)
+	loanType := fmt.Sprintf("%v", agentCtx.Parameters["loan_type"])
+	amount, _ := strconv.ParseFloat(fmt.Sprintf("%v", agentCtx.Parameters["amount"]), 64)
 
 	// Get loan product details
 	product, err := a.loanDAO.GetLoanProduct(loanType)
@@ -160,7 +159,7 @@ func (a *LoanAgent) handleLoanApplication(ctx *models.AgentContext) *models.Agen
 
 	// Calculate EMI
 	tenure := 24 // Default tenure
-	if t, exists := ctx.Parameters["tenure"]; exists {
+	if t, exists := agentCtx.Parameters["tenure"]; exists {
 		tenure, _ = strconv.Atoi(fmt.Sprintf("%v", t))
 	}
 	application.Tenure = tenure
@@ -175,7 +174,7 @@ func (a *LoanAgent) handleLoanApplication(ctx *models.AgentContext) *models.Agen
 	}
 }
 
-func (a *LoanAgent) handleEligibilityCheck(ctx *models.AgentContext) *models.AgentResponse {
+func (a *LoanAgent) handleEligibilityCheck(agentCtx *models.AgentContext) *models.AgentResponse {
 	// Mock eligibility check - in real system, this would check credit score, income, etc.
 	eligibilityScore := 75.0       // Mock score
 	maxEligibleAmount := 1000000.0 // Mock amount
@@ -202,10 +201,10 @@ func (a *LoanAgent) handleEligibilityCheck(ctx *models.AgentContext) *models.Age
 	}
 }
 
-func (a *LoanAgent) handleEMICalculation(ctx *models.AgentContext) *models.AgentResponse {
-	amount, _ := strconv.ParseFloat(fmt.Sprintf("%v", ctx.Parameters["amount"]), 64)
-	rate, _ := strconv.ParseFloat(fmt.Sprintf("%v", ctx.Parameters["interest_rate"]), 64)
-	tenure, _ := strconv.ParseFloat(fmt.Sprintf("%v", ctx.Parameters["tenure"]), 64)
+func (a *LoanAgent) handleEMICalculation(agentCtx *models.AgentContext) *models.AgentResponse {
+	amount, _ := strconv.ParseFloat(fmt.Sprintf("%v", agentCtx.Parameters["amount"]), 64)
+	rate, _ := strconv.ParseFloat(fmt.Sprintf("%v", agentCtx.Parameters["interest_rate"]), 64)
+	tenure, _ := strconv.ParseFloat(fmt.Sprintf("%v", agentCtx.Parameters["tenure"]), 64)
 
 	emi := a.calculateEMI(amount, rate, tenure)
 	totalAmount := emi * tenure
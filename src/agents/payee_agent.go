@@ -1,9 +1,8 @@
 package agents
 
 import (
+	"context"
 	"fmt"
-	"strings"
-	"time"
 
 	"github.com/banking/ai-agents-banking/src/dao"
 	"github.com/banking/ai-agents-banking/src/models"
@@ -22,27 +21,22 @@ func NewAddPayeeAgent(payeeDAO *dao.PayeeDAO) *AddPayeeAgent {
 			Description: "Manages adding new payees and beneficiaries",
 			Tools:       []string{"add_payee", "verify_payee", "transfer_to_payee", "view_all_payees"},
 			Confidence:  0.9,
+			Routing: RoutingProfile{
+				Intents:  []string{"add_payee", "add_beneficiary", "new_payee", "register_payee"},
+				Keywords: map[string]float64{"add payee": 1.5, "new payee": 1.5, "add recipient": 1.5, "beneficiary": 1.0, "register": 0.5},
+				Topic:    "add a payee",
+			},
 		},
 		payeeDAO: payeeDAO,
 	}
 }
 
 func (a *AddPayeeAgent) CanHandle(intent string, message string) bool {
-	payeeIntents := []string{"add_payee", "add_beneficiary", "new_payee", "register_payee"}
-	for _, pi := range payeeIntents {
-		if intent == pi {
-			return true
-		}
+	if a.Routing.OwnsIntent(intent) {
+		return true
 	}
-
-	payeeKeywords := []string{"add payee", "new payee", "register", "beneficiary", "add recipient"}
-	lowerMsg := strings.ToLower(message)
-	for _, keyword := range payeeKeywords {
-		if strings.Contains(lowerMsg, keyword) {
-			return true
-		}
-	}
-	return false
+	score, _ := a.Routing.MatchKeywords(message)
+	return score > 0
 }
 
 func (a *AddPayeeAgent) GetRequiredParameters() []string {
@@ -76,8 +70,8 @@ func (a *AddPayeeAgent) ValidateParameters(params map[string]interface{}) []stri
 	return missing
 }
 
-func (a *AddPayeeAgent) Process(ctx *models.AgentContext) *models.AgentResponse {
-	missing := a.ValidateParameters(ctx.Parameters)
+func (a *AddPayeeAgent) Process(ctx context.Context, agentCtx *models.AgentContext) *models.AgentResponse {
+	missing := a.ValidateParameters(agentCtx.Parameters)
 	if len(missing) > 0 {
 		return &models.AgentResponse{
 			Message:           a.getQuestionForMissing(missing[0]),
@@ -87,12 +81,12 @@ func (a *AddPayeeAgent) Process(ctx *models.AgentContext) *models.AgentResponse
 		}
 	}
 
-	payeeName := fmt.Sprintf("%v", ctx.Parameters["payee_name"])
-	accountNumber := fmt.Sprintf("%v", ctx.Parameters["account_number"])
-	ifscCode := fmt.Sprintf("%v", ctx.Parameters["ifsc_code"])
+	payeeName := fmt.Sprintf("%v", agentCtx.Parameters["payee_name"])
+	accountNumber := fmt.Sprintf("%v", agentCtx.Parameters["account_number"])
+	ifscCode := fmt.Sprintf("%v", agentCtx.Parameters["ifsc_code"])
 
 	// Check if payee already exists
-	existingPayee, err := a.payeeDAO.GetPayeeByAccount(ctx.UserID, accountNumber)
+	existingPayee, err := a.payeeDAO.GetPayeeByAccount(agentCtx.UserID, accountNumber)
 	if err != nil {
 		return &models.AgentResponse{
 			Message:   "Failed to check existing payees",
@@ -112,35 +106,42 @@ func (a *AddPayeeAgent) Process(ctx *models.AgentContext) *models.AgentResponse
 	// Get bank name from IFSC
 	bankName := utils.GetBankNameFromIFSC(ifscCode)
 
-	// Create new payee
-	payee := models.Payee{
-		ID:         fmt.Sprintf("PAYEE_%d", time.Now().UnixNano()),
-		Name:       payeeName,
-		AccountNo:  accountNumber,
-		BankName:   bankName,
-		IFSCCode:   ifscCode,
-		PayeeType:  "External",
-		AddedDate:  time.Now(),
-		IsActive:   true,
-		IsVerified: false, // Would be verified via OTP in real system
+	// New beneficiaries are only saved once the user has confirmed them
+	if !agentCtx.Confirmed {
+		return &models.AgentResponse{
+			Message:              fmt.Sprintf("Add payee %s, account ****%s at %s (IFSC %s)", payeeName, lastFour(accountNumber), bankName, ifscCode),
+			AgentName:            a.Name,
+			RequiresConfirmation: true,
+			ToolName:             "add_payee",
+			ToolParams: map[string]interface{}{
+				"name":           payeeName,
+				"account_number": accountNumber,
+				"ifsc_code":      ifscCode,
+			},
+		}
 	}
 
-	// Add to user's payee list
-	err = a.payeeDAO.AddUserPayee(ctx.UserID, payee)
-	if err != nil {
+	if err := ctx.Err(); err != nil {
 		return &models.AgentResponse{
-			Message:   "Failed to add payee",
+			Message:   "Adding the payee was cancelled. Nothing was saved.",
 			AgentName: a.Name,
-			Data:      err,
+			Data:      map[string]interface{}{"error": err.Error()},
 		}
 	}
 
+	// The add_payee tool saves the payee to this user's list
 	return &models.AgentResponse{
 		Message: fmt.Sprintf("✅ **Payee Added Successfully!**\n\n👤 Name: %s\n🏦 Bank: %s\n💳 Account: ****%s\n🏛️ IFSC: %s\n\n⚠️ Payee will be verified within 24 hours for enhanced security.",
-			payeeName, bankName, accountNumber[len(accountNumber)-4:], ifscCode),
-		Data:      payee,
-		Actions:   a.Tools,
-		AgentName: a.Name,
+			payeeName, bankName, lastFour(accountNumber), ifscCode),
+		Actions:      a.Tools,
+		AgentName:    a.Name,
+		RequiresTool: true,
+		ToolName:     "add_payee",
+		ToolParams: map[string]interface{}{
+			"name":           payeeName,
+			"account_number": accountNumber,
+			"ifsc_code":      ifscCode,
+		},
 	}
 }
 
@@ -2,28 +2,75 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"time"
 )
 
 type Config struct {
-	Port          string
-	LlamaURL      string
-	TokenExpiry   time.Duration
-	SessionExpiry time.Duration
-	BufferSize    int
-	LogLevel      string
-	Environment   string
+	Port               string
+	LlamaURL           string // Server or endpoint URL of the LLM provider
+	LLMProvider        string // ollama, ollama-chat or openai
+	LLMModel           string
+	LLMAPIKey          string
+	LLMFallbackModel   string        // Asked when the primary model is missing or failing; empty for none
+	LLMRetries         int           // Retries of a request that failed before any reply text arrived
+	LLMBreakerFailures int           // Failed requests in a row that open the circuit breaker; 0 disables it
+	LLMBreakerCooldown time.Duration // How long an open breaker answers without the model before probing it
+	LLMCassettes       string        // record or replay LLM answers as cassettes; empty to do neither
+	LLMCassetteDir     string
+	FakeLLM            string        // Serve the LLM from the built-in fake Ollama: "default" or a script file
+	StreamRate         float64       // Default tokens per second streamed replies are smoothed to; 0 passes tokens through
+	PromptTokenBudget  int           // Tokens a prompt may take, leaving the rest of the context window to the reply
+	PromptDir          string        // Directory of the system prompt templates
+	IntentCatalog      string        // JSON file of the intents recognised in messages
+	IntentReload       time.Duration // How often the intent catalog is checked for changes; 0 never reloads it
+	TokenExpiry        time.Duration
+	SessionExpiry      time.Duration
+	BufferSize         int
+	LogLevel           string
+	Environment        string
+	ConfirmationExpiry time.Duration
+	MaxToolRounds      int // Function-calling rounds per turn; 0 disables function calling
+	ToolTimeout        time.Duration
+	ToolTimeouts       map[string]time.Duration // Per-tool overrides of ToolTimeout
+	RoutingMargin      float64                  // Agents scoring closer than this make the assistant ask which was meant
+	OpsToken           string                   // Operator token for operational endpoints; empty leaves them unexposed
 }
 
 func New() *Config {
 	return &Config{
-		Port:          getEnv("PORT", "8080"),
-		LlamaURL:      getEnv("LLAMA_URL", "http://localhost:11434/api/generate"),
-		TokenExpiry:   24 * time.Hour,
-		SessionExpiry: 30 * time.Minute,
-		BufferSize:    256,
-		LogLevel:      getEnv("LOG_LEVEL", "INFO"),
-		Environment:   getEnv("ENVIRONMENT", "development"),
+		Port:               getEnv("PORT", "8080"),
+		LlamaURL:           getEnv("LLM_URL", getEnv("LLAMA_URL", "http://localhost:11434/api/generate")),
+		LLMProvider:        getEnv("LLM_PROVIDER", "ollama"),
+		LLMModel:           getEnv("LLM_MODEL", "llama3"),
+		LLMAPIKey:          getEnv("LLM_API_KEY", ""),
+		LLMFallbackModel:   getEnv("LLM_FALLBACK_MODEL", ""),
+		LLMRetries:         getEnvInt("LLM_RETRIES", 2),
+		LLMBreakerFailures: getEnvInt("LLM_BREAKER_FAILURES", 3),
+		LLMBreakerCooldown: getEnvDuration("LLM_BREAKER_COOLDOWN", 30*time.Second),
+		LLMCassettes:       getEnv("LLM_CASSETTES", ""),
+		LLMCassetteDir:     getEnv("LLM_CASSETTE_DIR", "testdata/cassettes"),
+		FakeLLM:            getEnv("FAKE_LLM", ""),
+		StreamRate:         getEnvFloat("STREAM_TOKENS_PER_SECOND", 0),
+		PromptTokenBudget:  getEnvInt("PROMPT_TOKEN_BUDGET", 4096),
+		PromptDir:          getEnv("PROMPT_DIR", "prompts"),
+		IntentCatalog:      getEnv("INTENT_CATALOG", "intents.json"),
+		IntentReload:       getEnvDuration("INTENT_RELOAD_INTERVAL", 5*time.Second),
+		TokenExpiry:        24 * time.Hour,
+		SessionExpiry:      30 * time.Minute,
+		BufferSize:         256,
+		LogLevel:           getEnv("LOG_LEVEL", "INFO"),
+		Environment:        getEnv("ENVIRONMENT", "development"),
+		ConfirmationExpiry: 5 * time.Minute,
+		MaxToolRounds:      getEnvInt("MAX_TOOL_ROUNDS", 5),
+		ToolTimeout:        10 * time.Second,
+		ToolTimeouts: map[string]time.Duration{
+			"fund_transfer": 30 * time.Second,
+			"add_payee":     15 * time.Second,
+			"get_weather":   5 * time.Second,
+		},
+		RoutingMargin: getEnvFloat("ROUTING_MARGIN", 0.25),
+		OpsToken:      getEnv("OPS_TOKEN", ""),
 	}
 }
 
@@ -33,3 +80,24 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
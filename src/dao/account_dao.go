@@ -2,6 +2,7 @@ package dao
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -79,6 +80,69 @@ func (d *AccountDAO) UpdateAccountBalance(userID, accountID string, newBalance f
 	return fmt.Errorf("account not found")
 }
 
+// DebitAccount atomically withdraws amount from an account of the user,
+// refusing to take the balance below zero or to move an amount that is not a
+// positive, finite number
+func (d *AccountDAO) DebitAccount(userID, accountID string, amount float64) (*models.Account, error) {
+	if err := checkAmount(amount); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	accounts, exists := d.userAccounts[userID]
+	if !exists {
+		return nil, fmt.Errorf("no accounts found for user")
+	}
+
+	for i := range accounts {
+		if accounts[i].AccountID == accountID {
+			if accounts[i].Balance < amount {
+				return nil, fmt.Errorf("insufficient balance: available ₹%.2f, required ₹%.2f", accounts[i].Balance, amount)
+			}
+			accounts[i].Balance -= amount
+			accounts[i].LastUpdated = time.Now()
+			account := accounts[i]
+			return &account, nil
+		}
+	}
+
+	return nil, fmt.Errorf("account not found")
+}
+
+// CreditAccount atomically deposits amount into an account of the user
+func (d *AccountDAO) CreditAccount(userID, accountID string, amount float64) (*models.Account, error) {
+	if err := checkAmount(amount); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	accounts, exists := d.userAccounts[userID]
+	if !exists {
+		return nil, fmt.Errorf("no accounts found for user")
+	}
+
+	for i := range accounts {
+		if accounts[i].AccountID == accountID {
+			accounts[i].Balance += amount
+			accounts[i].LastUpdated = time.Now()
+			account := accounts[i]
+			return &account, nil
+		}
+	}
+
+	return nil, fmt.Errorf("account not found")
+}
+
+// checkAmount rejects amounts no balance may move by: zero, negative, NaN or infinite
+func checkAmount(amount float64) error {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) || amount <= 0 {
+		return fmt.Errorf("invalid amount %v", amount)
+	}
+	return nil
+}
+
 func (d *AccountDAO) initializeMockData() {
 	d.userAccounts["user123"] = []models.Account{
 		{
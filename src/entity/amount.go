@@ -0,0 +1,195 @@
+package entity
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Amount is a sum of money in rupees
+type Amount struct {
+	Rupees float64
+	Span
+}
+
+// maxBareDigits is the longest number read as an amount without a currency sign, a
+// multiplier or digit grouping. Longer ones are account numbers.
+const maxBareDigits = 8
+
+var (
+	// ₹1,50,000 · Rs. 500/- · Rs500 · 2.5 lakh · 5k · 1 crore · 20000 rupees
+	amountRegex = regexp.MustCompile(`(?i)(?:(₹\s*|\b(?:rs\.?|inr)\s*)|\b)(\d+(?:,\d+)*(?:\.\d+)?)(?:\s*(k|thousand|lakhs?|lacs?|crores?|cr|million|mn)\b)?(\s*/-|\s*(?:rupees?|rs|inr)\b)?`)
+	wordRegex   = regexp.MustCompile(`(?i)\b[a-z]+\b`)
+	// A currency word right after an amount written in words, as in "fifty rupees"
+	rupeeWordRegex = regexp.MustCompile(`(?i)^\s*(?:rupees?|rs|inr)\b`)
+)
+
+var multipliers = map[string]float64{
+	"k": 1e3, "thousand": 1e3,
+	"lakh": 1e5, "lakhs": 1e5, "lac": 1e5, "lacs": 1e5,
+	"crore": 1e7, "crores": 1e7, "cr": 1e7,
+	"million": 1e6, "mn": 1e6,
+}
+
+// scaleWords multiply the number words before them
+var scaleWords = map[string]float64{
+	"hundred": 100, "thousand": 1e3, "lakh": 1e5, "lakhs": 1e5, "lac": 1e5, "lacs": 1e5,
+	"crore": 1e7, "crores": 1e7, "million": 1e6,
+}
+
+var smallNumbers = map[string]float64{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7,
+	"eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13,
+	"fourteen": 14, "fifteen": 15, "sixteen": 16, "seventeen": 17, "eighteen": 18,
+	"nineteen": 19, "twenty": 20, "thirty": 30, "forty": 40, "fifty": 50, "sixty": 60,
+	"seventy": 70, "eighty": 80, "ninety": 90,
+}
+
+// FindAmount finds the amount of money a message is about. An amount marked as money,
+// by a currency sign, a multiplier such as lakh or digit grouping, is preferred over a
+// bare number; amounts in words need a scale word (hundred, thousand, lakh, crore) or
+// a currency word. Percentages are not amounts.
+func FindAmount(text string) (Amount, bool) {
+	var bare *Amount
+	for _, loc := range amountRegex.FindAllStringSubmatchIndex(text, -1) {
+		if loc[1] < len(text) && (text[loc[1]] == '%' || isWordByte(text[loc[1]])) {
+			continue // A percentage, or a number such as the 5 of "5th"
+		}
+		digits := text[loc[4]:loc[5]]
+		value, err := strconv.ParseFloat(strings.ReplaceAll(digits, ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		marked := loc[2] >= 0 || loc[8] >= 0 || strings.Contains(digits, ",")
+		if loc[6] >= 0 {
+			value *= multipliers[strings.ToLower(text[loc[6]:loc[7]])]
+			marked = true
+		}
+
+		amount := Amount{Rupees: value, Span: spanOf(text, loc[0], loc[1])}
+		amount.Span = trimSpan(text, amount.Span)
+		if marked {
+			return amount, true
+		}
+		if bare == nil && len(strings.SplitN(digits, ".", 2)[0]) <= maxBareDigits {
+			bare = &amount
+		}
+	}
+
+	if amount, ok := findAmountInWords(text); ok {
+		return amount, true
+	}
+	if bare != nil {
+		return *bare, true
+	}
+	return Amount{}, false
+}
+
+// ParseNumberWords reads a number written in words, in the Indian system: "two lakh
+// fifty thousand" is 250000 and "twenty five hundred" is 2500
+func ParseNumberWords(text string) (float64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return r == ' ' || r == '-' || r == ',' })
+	return parseNumberWords(words)
+}
+
+func parseNumberWords(words []string) (float64, bool) {
+	if len(words) == 0 {
+		return 0, false
+	}
+	total, current := 0.0, 0.0
+	for i, word := range words {
+		switch {
+		case word == "and" && i > 0 && i < len(words)-1:
+		case (word == "a" || word == "an") && i < len(words)-1 && isScaleWord(words[i+1]):
+			current++
+		case word == "hundred":
+			if current == 0 {
+				current = 1
+			}
+			current *= 100
+		case isScaleWord(word):
+			if current == 0 {
+				current = 1
+			}
+			total += current * scaleWords[word]
+			current = 0
+		default:
+			n, ok := smallNumbers[word]
+			if !ok {
+				return 0, false
+			}
+			current += n
+		}
+	}
+	return total + current, true
+}
+
+func isScaleWord(word string) bool {
+	_, scale := scaleWords[word]
+	return scale
+}
+
+func isNumberWord(word string) bool {
+	_, small := smallNumbers[word]
+	return small || isScaleWord(word) || word == "and" || word == "a" || word == "an"
+}
+
+// findAmountInWords finds the first run of number words that reads as money
+func findAmountInWords(text string) (Amount, bool) {
+	matches := wordRegex.FindAllStringIndex(text, -1)
+	for i := 0; i < len(matches); {
+		if !isNumberWord(strings.ToLower(text[matches[i][0]:matches[i][1]])) {
+			i++
+			continue
+		}
+		// Extend the run over number words separated only by spaces, hyphens or commas
+		j := i + 1
+		for j < len(matches) && isNumberWord(strings.ToLower(text[matches[j][0]:matches[j][1]])) &&
+			strings.Trim(text[matches[j-1][1]:matches[j][0]], " -,") == "" {
+			j++
+		}
+		// A run never ends with a connecting word
+		for j > i && !isValueWord(strings.ToLower(text[matches[j-1][0]:matches[j-1][1]])) {
+			j--
+		}
+		if j == i {
+			i++
+			continue
+		}
+
+		words := make([]string, 0, j-i)
+		scaled := false
+		for _, m := range matches[i:j] {
+			word := strings.ToLower(text[m[0]:m[1]])
+			words = append(words, word)
+			scaled = scaled || isScaleWord(word)
+		}
+		start, end := matches[i][0], matches[j-1][1]
+		currency := rupeeWordRegex.FindStringIndex(text[end:])
+		if value, ok := parseNumberWords(words); ok && (scaled || currency != nil) {
+			if currency != nil {
+				end += currency[1]
+			}
+			return Amount{Rupees: value, Span: spanOf(text, start, end)}, true
+		}
+		i = j
+	}
+	return Amount{}, false
+}
+
+func isValueWord(word string) bool {
+	_, small := smallNumbers[word]
+	return small || isScaleWord(word)
+}
+
+// trimSpan drops the spaces a pattern matched at either end
+func trimSpan(text string, span Span) Span {
+	start, end := span.Start, span.End
+	for start < end && text[start] == ' ' {
+		start++
+	}
+	for end > start && text[end-1] == ' ' {
+		end--
+	}
+	return spanOf(text, start, end)
+}
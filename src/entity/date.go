@@ -0,0 +1,231 @@
+package entity
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tenure is a length of time, such as the term of a deposit or loan
+type Tenure struct {
+	Count int
+	Unit  string // days, months or years
+	Span
+}
+
+// Months is the tenure in whole months; days are rounded down
+func (t Tenure) Months() int {
+	switch t.Unit {
+	case "years":
+		return t.Count * 12
+	case "days":
+		return t.Count / 30
+	}
+	return t.Count
+}
+
+// Date is a point in time a message refers to. Dates without a time of day are at
+// midnight.
+type Date struct {
+	Time time.Time
+	Span
+}
+
+const numberWordPattern = `zero|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|thirteen|fourteen|fifteen|sixteen|seventeen|eighteen|nineteen|twenty|thirty|forty|fifty|sixty|seventy|eighty|ninety`
+
+var (
+	// 18 months · 2 yrs · 1.5 years · ninety days · six weeks
+	tenureRegex = regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?|(?:(?:` + numberWordPattern + `)[\s-]?)+)\s*(days?|weeks?|wks?|months?|mo|mos|mths?|years?|yrs?)\b`)
+
+	numericDateRegex  = regexp.MustCompile(`\b(\d{1,2})[/.-](\d{1,2})[/.-](\d{4}|\d{2})\b`)
+	relativeDayRegex  = regexp.MustCompile(`(?i)\b(day\s+after\s+tomorrow|tomorrow|today|tonight|yesterday)\b`)
+	relativeSpanRegex = regexp.MustCompile(`(?i)\b(?:in|after)\s+(\d+|a|an|` + numberWordPattern + `)\s+(minutes?|mins?|hours?|hrs?|days?|weeks?|months?)\b`)
+	weekdayRegex      = regexp.MustCompile(`(?i)\b(?:(next|this|coming)\s+)?(monday|tuesday|wednesday|thursday|friday|saturday|sunday)\b`)
+	monthDayRegex     = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?(` + monthPattern + `)\b(?:,?\s+(\d{4}))?`)
+	dayMonthRegex     = regexp.MustCompile(`(?i)\b(` + monthPattern + `)\s+(\d{1,2})(?:st|nd|rd|th)?\b(?:,?\s+(\d{4}))?`)
+	ordinalDayRegex   = regexp.MustCompile(`(?i)\b(?:(?:on|by|before|from)\s+(?:the\s+)?|the\s+)(\d{1,2})(?:st|nd|rd|th)\b`)
+	nextPeriodRegex   = regexp.MustCompile(`(?i)\bnext\s+(week|month)\b`)
+)
+
+const monthPattern = `jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?`
+
+// FindTenure finds the first length of time in text. Weeks are counted in days and
+// fractional years in months, so "1.5 years" is 18 months.
+func FindTenure(text string) (Tenure, bool) {
+	for _, loc := range tenureRegex.FindAllStringSubmatchIndex(text, -1) {
+		number := strings.TrimSpace(text[loc[2]:loc[3]])
+		count, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			var ok bool
+			if count, ok = ParseNumberWords(number); !ok {
+				continue
+			}
+		}
+		if count <= 0 {
+			continue
+		}
+
+		tenure := Tenure{Span: spanOf(text, loc[0], loc[1])}
+		switch unit := strings.ToLower(text[loc[4]:loc[5]]); {
+		case strings.HasPrefix(unit, "d"):
+			tenure.Count, tenure.Unit = int(count), "days"
+		case strings.HasPrefix(unit, "w"):
+			tenure.Count, tenure.Unit = int(count*7), "days"
+		case strings.HasPrefix(unit, "y"):
+			if count == float64(int(count)) {
+				tenure.Count, tenure.Unit = int(count), "years"
+			} else {
+				tenure.Count, tenure.Unit = int(count*12+0.5), "months"
+			}
+		default:
+			tenure.Count, tenure.Unit = int(count), "months"
+		}
+		if tenure.Count > 0 {
+			return tenure, true
+		}
+	}
+	return Tenure{}, false
+}
+
+// FindDate finds the first date or relative time in text, read against now:
+// "15/11/2026" (day first), "today", "tomorrow", "in 3 days", "in 2 hours",
+// "next Friday", "5th November", "Nov 5", "on the 5th" and "next month". A weekday or a
+// date without a year is the next one to come; "next Friday" is the first Friday
+// after today and "this Friday" may be today.
+func FindDate(text string, now time.Time) (Date, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if loc := numericDateRegex.FindStringSubmatchIndex(text); loc != nil {
+		day, _ := strconv.Atoi(text[loc[2]:loc[3]])
+		month, _ := strconv.Atoi(text[loc[4]:loc[5]])
+		year, _ := strconv.Atoi(text[loc[6]:loc[7]])
+		if year < 100 {
+			year += 2000
+		}
+		if t, ok := makeDate(year, time.Month(month), day, now.Location()); ok {
+			return Date{Time: t, Span: spanOf(text, loc[0], loc[1])}, true
+		}
+	}
+
+	if loc := relativeDayRegex.FindStringSubmatchIndex(text); loc != nil {
+		offset := 0
+		switch word := strings.ToLower(text[loc[2]:loc[3]]); {
+		case strings.HasPrefix(word, "day"):
+			offset = 2
+		case word == "tomorrow":
+			offset = 1
+		case word == "yesterday":
+			offset = -1
+		}
+		return Date{Time: today.AddDate(0, 0, offset), Span: spanOf(text, loc[0], loc[1])}, true
+	}
+
+	if loc := relativeSpanRegex.FindStringSubmatchIndex(text); loc != nil {
+		number := strings.ToLower(text[loc[2]:loc[3]])
+		count, err := strconv.Atoi(number)
+		if err != nil {
+			if number == "a" || number == "an" {
+				count = 1
+			} else {
+				words, _ := ParseNumberWords(number)
+				count = int(words)
+			}
+		}
+		var t time.Time
+		switch unit := strings.ToLower(text[loc[4]:loc[5]]); {
+		case strings.HasPrefix(unit, "min"):
+			t = now.Add(time.Duration(count) * time.Minute)
+		case strings.HasPrefix(unit, "h"):
+			t = now.Add(time.Duration(count) * time.Hour)
+		case strings.HasPrefix(unit, "d"):
+			t = today.AddDate(0, 0, count)
+		case strings.HasPrefix(unit, "w"):
+			t = today.AddDate(0, 0, 7*count)
+		default:
+			t = today.AddDate(0, count, 0)
+		}
+		return Date{Time: t, Span: spanOf(text, loc[0], loc[1])}, true
+	}
+
+	if loc := weekdayRegex.FindStringSubmatchIndex(text); loc != nil {
+		weekday := parseWeekday(text[loc[4]:loc[5]])
+		days := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if days == 0 && (loc[2] < 0 || !strings.EqualFold(text[loc[2]:loc[3]], "this")) {
+			days = 7
+		}
+		return Date{Time: today.AddDate(0, 0, days), Span: spanOf(text, loc[0], loc[1])}, true
+	}
+
+	for _, re := range []*regexp.Regexp{monthDayRegex, dayMonthRegex} {
+		loc := re.FindStringSubmatchIndex(text)
+		if loc == nil {
+			continue
+		}
+		dayText, monthText := text[loc[2]:loc[3]], text[loc[4]:loc[5]]
+		if re == dayMonthRegex {
+			dayText, monthText = monthText, dayText
+		}
+		day, _ := strconv.Atoi(dayText)
+		month := parseMonth(monthText)
+		if loc[6] >= 0 {
+			year, _ := strconv.Atoi(text[loc[6]:loc[7]])
+			if t, ok := makeDate(year, month, day, now.Location()); ok {
+				return Date{Time: t, Span: spanOf(text, loc[0], loc[1])}, true
+			}
+			continue
+		}
+		for year := today.Year(); year <= today.Year()+1; year++ {
+			if t, ok := makeDate(year, month, day, now.Location()); ok && !t.Before(today) {
+				return Date{Time: t, Span: spanOf(text, loc[0], loc[1])}, true
+			}
+		}
+	}
+
+	if loc := ordinalDayRegex.FindStringSubmatchIndex(text); loc != nil {
+		day, _ := strconv.Atoi(text[loc[2]:loc[3]])
+		// The next month that has the day, starting with this one
+		for months := 0; months < 12 && day >= 1 && day <= 31; months++ {
+			first := time.Date(today.Year(), today.Month()+time.Month(months), 1, 0, 0, 0, 0, now.Location())
+			if t, ok := makeDate(first.Year(), first.Month(), day, now.Location()); ok && !t.Before(today) {
+				return Date{Time: t, Span: spanOf(text, loc[0], loc[1])}, true
+			}
+		}
+	}
+
+	if loc := nextPeriodRegex.FindStringSubmatchIndex(text); loc != nil {
+		t := today.AddDate(0, 0, 7)
+		if strings.EqualFold(text[loc[2]:loc[3]], "month") {
+			t = today.AddDate(0, 1, 0)
+		}
+		return Date{Time: t, Span: spanOf(text, loc[0], loc[1])}, true
+	}
+
+	return Date{}, false
+}
+
+// makeDate builds a date, rejecting days the month does not have
+func makeDate(year int, month time.Month, day int, loc *time.Location) (time.Time, bool) {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	return t, t.Year() == year && t.Month() == month && t.Day() == day
+}
+
+func parseWeekday(name string) time.Weekday {
+	name = strings.ToLower(name)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.ToLower(day.String()) == name {
+			return day
+		}
+	}
+	return time.Sunday
+}
+
+func parseMonth(name string) time.Month {
+	prefix := strings.ToLower(name)[:3]
+	for month := time.January; month <= time.December; month++ {
+		if strings.ToLower(month.String()[:3]) == prefix {
+			return month
+		}
+	}
+	return time.January
+}
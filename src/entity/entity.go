@@ -0,0 +1,105 @@
+// Package entity finds the values banking messages are written with, the way Indian
+// customers write them: rupee amounts in lakh and crore, with Indian digit grouping or
+// in words; tenures; dates and relative times; account-number suffixes and UPI IDs.
+// Values come back typed, with the span of the message they were read from.
+package entity
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Span is where in a message a value was found
+type Span struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"` // Byte offsets into the message
+	End   int    `json:"end"`
+}
+
+func spanOf(text string, start, end int) Span {
+	return Span{Text: text[start:end], Start: start, End: end}
+}
+
+// AccountSuffix is the last digits of an account number, as in "account ending 7890"
+type AccountSuffix struct {
+	Digits string
+	Span
+}
+
+// UPIID is a UPI virtual payment address, as in "priya@okaxis"
+type UPIID struct {
+	ID string
+	Span
+}
+
+var (
+	accountSuffixRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(?:ending|ends)(?:\s+(?:in|with))?\s+(?:x+|\*+)?(\d{3,6})\b`),
+		regexp.MustCompile(`(?i)\blast\s+(?:\d|four|three|six)\s+digits?\s+(?:are\s+|is\s+)?(\d{3,6})\b`),
+		regexp.MustCompile(`(?i)(?:\ba/c|\bacc(?:ount)?|\bacct)\.?\s*(?:no\.?\s*|number\s*)?(?:x{2,}|\*{2,})(\d{3,6})\b`),
+	}
+	upiIDRegex = regexp.MustCompile(`\b([a-zA-Z0-9][a-zA-Z0-9._-]{1,255}@[a-zA-Z][a-zA-Z0-9]{1,63})\b`)
+)
+
+// FindAccountSuffix finds the first account-number suffix in text
+func FindAccountSuffix(text string) (AccountSuffix, bool) {
+	for _, re := range accountSuffixRegexes {
+		if loc := re.FindStringSubmatchIndex(text); loc != nil {
+			return AccountSuffix{Digits: text[loc[2]:loc[3]], Span: spanOf(text, loc[0], loc[1])}, true
+		}
+	}
+	return AccountSuffix{}, false
+}
+
+// FindUPIID finds the first UPI ID in text. Email addresses are not UPI IDs: a handle
+// never has a dot after the @.
+func FindUPIID(text string) (UPIID, bool) {
+	for _, loc := range upiIDRegex.FindAllStringSubmatchIndex(text, -1) {
+		if loc[1] < len(text) && text[loc[1]] == '.' && loc[1]+1 < len(text) && isWordByte(text[loc[1]+1]) {
+			continue
+		}
+		return UPIID{ID: strings.ToLower(text[loc[2]:loc[3]]), Span: spanOf(text, loc[0], loc[1])}, true
+	}
+	return UPIID{}, false
+}
+
+// Extract finds every kind of value in a message and returns them by the parameter
+// names agents use: amount (float64 rupees), tenure (int) with tenure_unit (days,
+// months or years), date (time.Time), account_suffix and upi_id. Relative dates are
+// read against now. Each value is taken out of the message before the next kind is
+// looked for, so the digits of a UPI ID, an account suffix, a date or a tenure are
+// never read as an amount.
+func Extract(message string, now time.Time) map[string]interface{} {
+	found := make(map[string]interface{})
+	text := message
+	consume := func(span Span) {
+		text = text[:span.Start] + strings.Repeat(" ", span.End-span.Start) + text[span.End:]
+	}
+
+	if upi, ok := FindUPIID(text); ok {
+		found["upi_id"] = upi.ID
+		consume(upi.Span)
+	}
+	if suffix, ok := FindAccountSuffix(text); ok {
+		found["account_suffix"] = suffix.Digits
+		consume(suffix.Span)
+	}
+	if date, ok := FindDate(text, now); ok {
+		found["date"] = date.Time
+		consume(date.Span)
+	}
+	if tenure, ok := FindTenure(text); ok {
+		found["tenure"] = tenure.Count
+		found["tenure_unit"] = tenure.Unit
+		consume(tenure.Span)
+	}
+	if amount, ok := FindAmount(text); ok {
+		found["amount"] = amount.Rupees
+	}
+	return found
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
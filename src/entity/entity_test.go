@@ -0,0 +1,186 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// now is a Wednesday
+var now = time.Date(2026, time.October, 14, 10, 30, 0, 0, time.UTC)
+
+func TestFindAmount(t *testing.T) {
+	tests := []struct {
+		text   string
+		rupees float64
+		span   string
+		ok     bool
+	}{
+		{"send ₹1,50,000 to Ravi", 150000, "₹1,50,000", true},
+		{"transfer Rs. 500/- now", 500, "Rs. 500/-", true},
+		{"send Rs500 to Ravi", 500, "Rs500", true},
+		{"send INR500 to Ravi", 500, "INR500", true},
+		{"transfer rs.2,500 now", 2500, "rs.2,500", true},
+		{"pay ₹750", 750, "₹750", true},
+		{"send from acc500 please", 0, "", false},
+		{"pay 5k to mom", 5000, "5k", true},
+		{"a loan of 2.5 lakh", 250000, "2.5 lakh", true},
+		{"1 crore", 1e7, "1 crore", true},
+		{"send 20000 rupees", 20000, "20000 rupees", true},
+		{"two lakh fifty thousand please", 250000, "two lakh fifty thousand", true},
+		{"fifty rupees to Priya", 50, "fifty rupees", true},
+		{"twenty five hundred", 2500, "twenty five hundred", true},
+		{"pay 700 to 1234567890", 700, "700", true},
+		{"send 300 on the 5th", 300, "300", true},
+		{"at 7.5% interest", 0, "", false},
+		{"on the 5th", 0, "", false},
+		{"account 123456789012", 0, "", false},
+		{"one of my payees", 0, "", false},
+		{"hello", 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			amount, ok := FindAmount(tt.text)
+			if ok != tt.ok || amount.Rupees != tt.rupees || amount.Text != tt.span {
+				t.Errorf("FindAmount(%q) = %v %q, %v; want %v %q, %v", tt.text, amount.Rupees, amount.Text, ok, tt.rupees, tt.span, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFindTenure(t *testing.T) {
+	tests := []struct {
+		text   string
+		count  int
+		unit   string
+		months int
+		ok     bool
+	}{
+		{"for 18 months", 18, "months", 18, true},
+		{"2 yrs", 2, "years", 24, true},
+		{"1.5 years", 18, "months", 18, true},
+		{"ninety days", 90, "days", 3, true},
+		{"six weeks", 42, "days", 1, true},
+		{"0 months", 0, "", 0, false},
+		{"no tenure here", 0, "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			tenure, ok := FindTenure(tt.text)
+			if ok != tt.ok || tenure.Count != tt.count || tenure.Unit != tt.unit || tenure.Months() != tt.months {
+				t.Errorf("FindTenure(%q) = %d %s (%d months), %v; want %d %s (%d months), %v",
+					tt.text, tenure.Count, tenure.Unit, tenure.Months(), ok, tt.count, tt.unit, tt.months, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFindDate(t *testing.T) {
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		text string
+		want time.Time
+		ok   bool
+	}{
+		{"on 15/11/2026", day(time.November, 15), true},
+		{"on 15-11-26", day(time.November, 15), true},
+		{"on 31/02/2026", time.Time{}, false},
+		{"today", day(time.October, 14), true},
+		{"tomorrow", day(time.October, 15), true},
+		{"day after tomorrow", day(time.October, 16), true},
+		{"in 3 days", day(time.October, 17), true},
+		{"in two hours", now.Add(2 * time.Hour), true},
+		{"next Friday", day(time.October, 16), true},
+		{"next Wednesday", day(time.October, 21), true},
+		{"this Wednesday", day(time.October, 14), true},
+		{"5th November", day(time.November, 5), true},
+		{"Nov 5", day(time.November, 5), true},
+		{"1st October", time.Date(2027, time.October, 1, 0, 0, 0, 0, time.UTC), true},
+		{"on the 5th", day(time.November, 5), true},
+		{"on the 20th", day(time.October, 20), true},
+		{"next month", day(time.November, 14), true},
+		{"no date", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			date, ok := FindDate(tt.text, now)
+			if ok != tt.ok || !date.Time.Equal(tt.want) {
+				t.Errorf("FindDate(%q) = %v, %v; want %v, %v", tt.text, date.Time, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFindAccountSuffix(t *testing.T) {
+	tests := []struct {
+		text   string
+		digits string
+		ok     bool
+	}{
+		{"the account ending 1234", "1234", true},
+		{"card ends with xx5678", "5678", true},
+		{"last four digits are 4321", "4321", true},
+		{"a/c no. XX9012", "9012", true},
+		{"acct ****3456", "3456", true},
+		{"account 1234", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			suffix, ok := FindAccountSuffix(tt.text)
+			if ok != tt.ok || suffix.Digits != tt.digits {
+				t.Errorf("FindAccountSuffix(%q) = %q, %v; want %q, %v", tt.text, suffix.Digits, ok, tt.digits, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFindUPIID(t *testing.T) {
+	tests := []struct {
+		text string
+		id   string
+		ok   bool
+	}{
+		{"pay priya@okaxis 500", "priya@okaxis", true},
+		{"to Ravi.Kumar@YBL.", "ravi.kumar@ybl", true},
+		{"mail me at ravi@example.com", "", false},
+		{"no id here", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			upi, ok := FindUPIID(tt.text)
+			if ok != tt.ok || upi.ID != tt.id {
+				t.Errorf("FindUPIID(%q) = %q, %v; want %q, %v", tt.text, upi.ID, ok, tt.id, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		message string
+		want    map[string]interface{}
+	}{
+		{
+			"send 2,000 to ravi@okaxis tomorrow",
+			map[string]interface{}{"amount": 2000.0, "upi_id": "ravi@okaxis", "date": time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			"fixed deposit of 1 lakh for 12 months",
+			map[string]interface{}{"amount": 1e5, "tenure": 12, "tenure_unit": "months"},
+		},
+		{
+			"pay the account ending 4455 on 20/10/2026",
+			map[string]interface{}{"account_suffix": "4455", "date": time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)},
+		},
+		{"what is my balance", map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if got := Extract(tt.message, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract(%q) = %v; want %v", tt.message, got, tt.want)
+			}
+		})
+	}
+}
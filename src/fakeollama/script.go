@@ -0,0 +1,117 @@
+package fakeollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Script decides how the fake server answers. Rules are tried in order and the first
+// one matching a request answers it; requests no rule matches get Default.
+type Script struct {
+	Models       []string `json:"models,omitempty"`         // Models the server has; others answer 404. Empty accepts any
+	NoTools      bool     `json:"no_tools,omitempty"`       // Refuse requests offering tools, like models without tool support
+	Default      string   `json:"default,omitempty"`        // Reply when no rule matches
+	LatencyMS    int      `json:"latency_ms,omitempty"`     // Delay before the first byte of every answer
+	TokenDelayMS int      `json:"token_delay_ms,omitempty"` // Delay between streamed chunks
+	Rules        []Rule   `json:"rules,omitempty"`
+}
+
+// Rule answers the requests it matches with a reply, tool calls or a failure
+type Rule struct {
+	Match      string     `json:"match,omitempty"`       // Regular expression, case-insensitive, searched in the last user message
+	Role       string     `json:"role,omitempty"`        // Role of the last message the rule applies to: user (default) or tool
+	Endpoint   string     `json:"endpoint,omitempty"`    // generate or chat; empty for both
+	Reply      string     `json:"reply,omitempty"`       // Text of the reply; a tool rule may use {{result}} for the tool message
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`  // Calls made instead of replying, when the request offers tools
+	Status     int        `json:"status,omitempty"`      // Fail with this HTTP status and Error
+	Error      string     `json:"error,omitempty"`       // Error reported; without Status it ends the stream after BreakAfter chunks
+	BreakAfter int        `json:"break_after,omitempty"` // Chunks streamed before Error ends the stream
+	Times      int        `json:"times,omitempty"`       // Apply to this many matching requests only; 0 for every one
+	LatencyMS  int        `json:"latency_ms,omitempty"`  // Delay before answering, on top of the script's latency
+
+	pattern *regexp.Regexp
+	used    int
+}
+
+// ToolCall is a function call a rule makes
+type ToolCall struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// defaultReply answers requests no rule matches when the script sets no Default
+const defaultReply = "This is a scripted reply from the offline model."
+
+// DefaultScript answers every request with a fixed reply
+func DefaultScript() *Script {
+	return &Script{Default: defaultReply}
+}
+
+// LoadScript reads a script from a JSON file
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &script, nil
+}
+
+// compile checks the rules and prepares their patterns
+func (s *Script) compile() error {
+	if s.Default == "" {
+		s.Default = defaultReply
+	}
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		pattern, err := regexp.Compile("(?i)" + rule.Match)
+		if err != nil {
+			return fmt.Errorf("rule %d: %v", i+1, err)
+		}
+		rule.pattern = pattern
+		if rule.Role == "" {
+			rule.Role = "user"
+		}
+	}
+	return nil
+}
+
+// hasModel reports whether the script serves the named model. Tags like :latest are
+// optional on either side.
+func (s *Script) hasModel(model string) bool {
+	if len(s.Models) == 0 {
+		return true
+	}
+	for _, name := range s.Models {
+		if name == model || strings.TrimSuffix(name, ":latest") == strings.TrimSuffix(model, ":latest") {
+			return true
+		}
+	}
+	return false
+}
+
+// match finds the rule answering a request and counts it as used. Callers hold the
+// server's lock.
+func (s *Script) match(endpoint string, last turn) *Rule {
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		if rule.Endpoint != "" && rule.Endpoint != endpoint {
+			continue
+		}
+		if rule.Role != last.role || !rule.pattern.MatchString(last.userMessage) {
+			continue
+		}
+		if rule.Times > 0 && rule.used >= rule.Times {
+			continue
+		}
+		rule.used++
+		return rule
+	}
+	return nil
+}
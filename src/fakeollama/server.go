@@ -0,0 +1,376 @@
+// Package fakeollama is a stand-in for an Ollama server, for running the chat path
+// without a model or network. It speaks /api/generate and /api/chat, streaming the
+// same NDJSON Ollama does, and answers from a Script that can also inject latency,
+// HTTP failures and streams that break off.
+package fakeollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Request is a request the server received, kept for assertions
+type Request struct {
+	Endpoint string    `json:"endpoint"` // generate or chat
+	Model    string    `json:"model"`
+	Stream   bool      `json:"stream"`
+	Prompt   string    `json:"prompt,omitempty"`
+	Messages []Message `json:"messages,omitempty"`
+	Tools    []string  `json:"tools,omitempty"` // Names of the tools offered
+}
+
+// Message is a chat message in the /api/chat format
+type Message struct {
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	ToolCalls []wireToolCall `json:"tool_calls,omitempty"`
+	ToolName  string         `json:"tool_name,omitempty"`
+}
+
+type wireToolCall struct {
+	Function struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	} `json:"function"`
+}
+
+type generateRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Stream *bool  `json:"stream"`
+}
+
+type chatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   *bool     `json:"stream"`
+	Tools    []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+}
+
+// turn is what a rule is matched against
+type turn struct {
+	role        string // Role of the last message
+	userMessage string // Content of the last user message
+	toolResult  string // Content of the last message when it is a tool result
+}
+
+// Server answers Ollama API requests from a script
+type Server struct {
+	mu       sync.Mutex
+	script   *Script
+	requests []Request
+	listener net.Listener
+}
+
+// New creates a server answering from script
+func New(script *Script) (*Server, error) {
+	if err := script.compile(); err != nil {
+		return nil, err
+	}
+	return &Server{script: script}, nil
+}
+
+// Listen serves on addr in the background and returns the base URL to point clients
+// at. An addr with port 0 picks a free port.
+func (s *Server) Listen(addr string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	go func() {
+		if err := http.Serve(listener, s); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Printf("[FakeOllama] Stopped: %v", err)
+		}
+	}()
+	return "http://" + listener.Addr().String(), nil
+}
+
+// Close stops serving what Listen started
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+// Requests returns the requests received so far, oldest first
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/" || r.URL.Path == "":
+		fmt.Fprint(w, "Ollama is running")
+	case r.URL.Path == "/api/version":
+		writeJSON(w, http.StatusOK, map[string]string{"version": "0.0.0-fake"})
+	case r.URL.Path == "/api/tags":
+		s.tags(w)
+	case r.URL.Path == "/api/generate" && r.Method == http.MethodPost:
+		s.generate(w, r)
+	case r.URL.Path == "/api/chat" && r.Method == http.MethodPost:
+		s.chat(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "404 page not found"})
+	}
+}
+
+func (s *Server) tags(w http.ResponseWriter) {
+	models := []map[string]string{}
+	for _, name := range s.script.Models {
+		models = append(models, map[string]string{"name": name, "model": name})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"models": models})
+}
+
+func (s *Server) generate(w http.ResponseWriter, r *http.Request) {
+	var req generateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	stream := req.Stream == nil || *req.Stream
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Endpoint: "generate", Model: req.Model, Stream: stream, Prompt: req.Prompt})
+	rule := s.script.match("generate", turn{role: "user", userMessage: lastHumanLine(req.Prompt)})
+	s.mu.Unlock()
+
+	if !s.prepare(w, r, req.Model, rule, false, stream) {
+		return
+	}
+	reply := s.reply(rule, turn{})
+
+	chunk := func(text string, done bool) interface{} {
+		line := map[string]interface{}{
+			"model":      req.Model,
+			"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+			"response":   text,
+			"done":       done,
+		}
+		if done {
+			line["done_reason"] = "stop"
+			line["eval_count"] = len(splitChunks(reply))
+		}
+		return line
+	}
+	if !stream {
+		writeJSON(w, http.StatusOK, chunk(reply, true))
+		return
+	}
+	s.stream(w, r, rule, reply, chunk)
+}
+
+func (s *Server) chat(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	stream := req.Stream == nil || *req.Stream
+	tools := make([]string, len(req.Tools))
+	for i, tool := range req.Tools {
+		tools[i] = tool.Function.Name
+	}
+
+	last := turn{role: "user"}
+	if n := len(req.Messages); n > 0 {
+		last.role = req.Messages[n-1].Role
+		if last.role == "tool" {
+			last.toolResult = req.Messages[n-1].Content
+		}
+	}
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			last.userMessage = req.Messages[i].Content
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Endpoint: "chat", Model: req.Model, Stream: stream, Messages: req.Messages, Tools: tools})
+	rule := s.script.match("chat", last)
+	s.mu.Unlock()
+
+	if !s.prepare(w, r, req.Model, rule, len(tools) > 0, stream) {
+		return
+	}
+
+	message := Message{Role: "assistant"}
+	if rule != nil && len(rule.ToolCalls) > 0 && len(tools) > 0 {
+		for _, call := range rule.ToolCalls {
+			var wire wireToolCall
+			wire.Function.Name = call.Name
+			wire.Function.Arguments = call.Arguments
+			message.ToolCalls = append(message.ToolCalls, wire)
+		}
+	} else {
+		message.Content = s.reply(rule, last)
+	}
+
+	chunk := func(text string, done bool) interface{} {
+		line := map[string]interface{}{
+			"model":      req.Model,
+			"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+			"message":    Message{Role: "assistant", Content: text},
+			"done":       done,
+		}
+		if done {
+			line["done_reason"] = "stop"
+		}
+		return line
+	}
+	if !stream || len(message.ToolCalls) > 0 {
+		// Ollama sends tool calls whole, even when streaming
+		line := chunk("", true).(map[string]interface{})
+		line["message"] = message
+		writeJSON(w, http.StatusOK, line)
+		return
+	}
+	s.stream(w, r, rule, message.Content, chunk)
+}
+
+// prepare waits out the latency and answers the failures the request gets. A stream
+// that would break off fails whole when the request is not streamed. It reports
+// whether a reply should follow.
+func (s *Server) prepare(w http.ResponseWriter, r *http.Request, model string, rule *Rule, offersTools bool, stream bool) bool {
+	latency := time.Duration(s.script.LatencyMS) * time.Millisecond
+	if rule != nil {
+		latency += time.Duration(rule.LatencyMS) * time.Millisecond
+	}
+	if !sleep(r.Context(), latency) {
+		return false
+	}
+
+	switch {
+	case !s.script.hasModel(model):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("model %q not found, try pulling it first", model)})
+		return false
+	case offersTools && s.script.NoTools:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%s does not support tools", model)})
+		return false
+	case rule != nil && rule.Status != 0:
+		message := rule.Error
+		if message == "" {
+			message = http.StatusText(rule.Status)
+		}
+		writeJSON(w, rule.Status, map[string]string{"error": message})
+		return false
+	case rule != nil && rule.Error != "" && !stream:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": rule.Error})
+		return false
+	}
+	return true
+}
+
+// reply is the text a rule answers with
+func (s *Server) reply(rule *Rule, last turn) string {
+	if rule == nil || rule.Reply == "" {
+		return s.script.Default
+	}
+	return strings.ReplaceAll(rule.Reply, "{{result}}", last.toolResult)
+}
+
+// stream writes reply as NDJSON chunks made by chunk, ending with a done line or,
+// when the rule breaks the stream, with an error line
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, rule *Rule, reply string, chunk func(text string, done bool) interface{}) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	delay := time.Duration(s.script.TokenDelayMS) * time.Millisecond
+
+	for i, piece := range splitChunks(reply) {
+		if rule != nil && rule.Error != "" && i == rule.BreakAfter {
+			break
+		}
+		if i > 0 && !sleep(r.Context(), delay) {
+			return
+		}
+		encoder.Encode(chunk(piece, false))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if rule != nil && rule.Error != "" {
+		encoder.Encode(map[string]string{"error": rule.Error})
+		return
+	}
+	encoder.Encode(chunk("", true))
+}
+
+// lastHumanLine finds the customer's message in a flattened /api/generate prompt
+func lastHumanLine(prompt string) string {
+	start := strings.LastIndex(prompt, "Human: ")
+	if start < 0 {
+		return prompt
+	}
+	message := prompt[start+len("Human: "):]
+	if end := strings.LastIndex(message, "\nAssistant:"); end >= 0 {
+		message = message[:end]
+	}
+	return message
+}
+
+// splitChunks cuts text into word-sized chunks that keep their leading whitespace,
+// the way model tokens usually arrive
+func splitChunks(text string) []string {
+	var chunks []string
+	start, afterSpace := 0, false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if i > start && space && !afterSpace {
+			chunks = append(chunks, text[start:i])
+			start = i
+		}
+		afterSpace = space
+	}
+	if start < len(text) {
+		chunks = append(chunks, text[start:])
+	}
+	return chunks
+}
+
+func sleep(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
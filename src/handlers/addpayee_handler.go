@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -68,7 +69,7 @@ func (h *PayeeHandler) CreatePayee(w http.ResponseWriter, r *http.Request) {
 	}
 
 	payee := models.Payee{
-		ID:         time.Now().Format("PAYEE_20060102150405"),
+		ID:         fmt.Sprintf("PAYEE_%d", time.Now().UnixNano()),
 		Name:       req.Name,
 		AccountNo:  req.AccountNo,
 		BankName:   req.BankName,
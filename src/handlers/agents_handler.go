@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/banking/ai-agents-banking/src/models"
 	"github.com/banking/ai-agents-banking/src/services"
 	"github.com/banking/ai-agents-banking/src/utils"
 	"github.com/gorilla/mux"
@@ -13,12 +14,14 @@ import (
 type AgentsHandler struct {
 	agentService   *services.AgentService
 	sessionService *services.SessionService
+	intents        *services.IntentCatalog
 }
 
-func NewAgentsHandler(agentService *services.AgentService, sessionService *services.SessionService) *AgentsHandler {
+func NewAgentsHandler(agentService *services.AgentService, sessionService *services.SessionService, intents *services.IntentCatalog) *AgentsHandler {
 	return &AgentsHandler{
 		agentService:   agentService,
 		sessionService: sessionService,
+		intents:        intents,
 	}
 }
 
@@ -115,3 +118,41 @@ func (h *AgentsHandler) GetAgentDetails(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(agentInfo)
 }
+
+// Routing shows how a message would be routed: the recognised intent, the ranking
+// of every agent and whether the assistant would ask the user to clarify
+func (h *AgentsHandler) Routing(w http.ResponseWriter, r *http.Request) {
+	session, ok := utils.GetUserSessionFromContext(r)
+	if !ok {
+		http.Error(w, "Session not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	message := r.URL.Query().Get("message")
+	if message == "" {
+		http.Error(w, `{"error": "message is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	intent := h.intents.RecognizeIntent(message)
+	decision := h.agentService.Route(&models.AgentContext{
+		SessionID:   session.ID,
+		UserID:      session.AccountID,
+		Message:     message,
+		Intent:      intent.Name,
+		CurrentStep: session.GetCurrentStep(),
+	})
+
+	response := map[string]interface{}{
+		"message":           message,
+		"intent":            intent.Name,
+		"intent_confidence": intent.Confidence,
+		"agent":             decision.Agent.GetName(),
+		"clarify":           decision.Clarify,
+		"ranking":           decision.Ranking,
+		"timestamp":         time.Now(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
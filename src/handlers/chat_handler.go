@@ -3,47 +3,50 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/banking/ai-agents-banking/src/models"
 	"github.com/banking/ai-agents-banking/src/services"
 	"github.com/banking/ai-agents-banking/src/utils"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// detachedStreamTimeout bounds a generation started with Stream, which no request waits on
+	detachedStreamTimeout = 5 * time.Minute
+	// defaultPollWait and maxPollWait bound how long Poll holds a request open for new
+	// content. maxPollWait stays below the server's write timeout so a poll always answers
+	// even where the deadline cannot be lifted.
+	defaultPollWait = 20 * time.Second
+	maxPollWait     = 25 * time.Second
 )
 
 type ChatHandler struct {
 	sessionService      *services.SessionService
-	agentService        *services.AgentService
 	conversationService *services.ConversationService
-	intentService       *services.IntentRecognitionService
-	llamaService        *services.LlamaService
-	toolRegistry        *services.ToolRegistry
+	orchestrator        *services.ChatOrchestrator
 }
 
 func NewChatHandler(
 	sessionService *services.SessionService,
-	agentService *services.AgentService,
 	conversationService *services.ConversationService,
-	intentService *services.IntentRecognitionService,
-	llamaService *services.LlamaService,
-	toolRegistry *services.ToolRegistry,
+	orchestrator *services.ChatOrchestrator,
 ) *ChatHandler {
 	return &ChatHandler{
 		sessionService:      sessionService,
-		agentService:        agentService,
 		conversationService: conversationService,
-		intentService:       intentService,
-		llamaService:        llamaService,
-		toolRegistry:        toolRegistry,
+		orchestrator:        orchestrator,
 	}
 }
 
 func (h *ChatHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	log.Printf("=== Chat request received from %s ===", r.RemoteAddr)
-	log.Printf("Request headers: %v", r.Header)
 	log.Printf("Request method: %s", r.Method)
 	log.Printf("Request URL: %s", r.URL.String())
 
@@ -60,294 +63,382 @@ func (h *ChatHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get session from context
+	session, ok := utils.GetUserSessionFromContext(r)
+	if !ok {
+		log.Printf("Session not found in context")
+		h.writeJSONError(w, http.StatusUnauthorized, "Session not found")
+		return
+	}
+
+	// A reconnecting client continues the stream it lost instead of sending the message again
+	if lastID := lastEventID(r); lastID != "" {
+		h.resumeStream(w, r, session, lastID)
+		return
+	}
+
+	req, ok := h.parseChatRequest(w, r, session)
+	if !ok {
+		return
+	}
+	if req.Message == "" {
+		h.writeJSONError(w, http.StatusBadRequest, "Message is required")
+		return
+	}
+
+	h.serveTurn(w, r, session, req, h.processTurn(session, req.Message))
+}
+
+// Regenerate phrases the last assistant reply again without repeating any operation.
+// Like ServeHTTP it streams the reply unless the request asks for "stream": false.
+func (h *ChatHandler) Regenerate(w http.ResponseWriter, r *http.Request) {
+	session, ok := utils.GetUserSessionFromContext(r)
+	if !ok {
+		h.writeJSONError(w, http.StatusUnauthorized, "Session not found")
+		return
+	}
+
+	req, ok := h.parseChatRequest(w, r, session)
+	if !ok {
+		return
+	}
+
+	h.serveTurn(w, r, session, req, func(ctx context.Context, streamingSession *models.StreamingSession, emit services.ChatEventSink) (*services.TurnResult, error) {
+		return h.orchestrator.Regenerate(ctx, session, streamingSession, emit)
+	})
+}
+
+// Edit replaces the last user message with the one in the request and answers it anew.
+// Like ServeHTTP it streams the reply unless the request asks for "stream": false.
+func (h *ChatHandler) Edit(w http.ResponseWriter, r *http.Request) {
+	session, ok := utils.GetUserSessionFromContext(r)
+	if !ok {
+		h.writeJSONError(w, http.StatusUnauthorized, "Session not found")
+		return
+	}
+
+	req, ok := h.parseChatRequest(w, r, session)
+	if !ok {
+		return
+	}
+	if req.Message == "" {
+		h.writeJSONError(w, http.StatusBadRequest, "Message is required")
+		return
+	}
+
+	h.serveTurn(w, r, session, req, func(ctx context.Context, streamingSession *models.StreamingSession, emit services.ChatEventSink) (*services.TurnResult, error) {
+		return h.orchestrator.EditLastMessage(ctx, session, req.Message, streamingSession, emit)
+	})
+}
+
+// serveTurn runs the turn and streams it over SSE, or answers with a single
+// ChatResponse when the request asks for "stream": false
+func (h *ChatHandler) serveTurn(w http.ResponseWriter, r *http.Request, session *models.UserSession, req models.ChatRequest, run turnFunc) {
+	if !req.Streaming() {
+		h.respondJSON(w, r, session, run)
+		return
+	}
+
 	// Set proper SSE headers first
 	h.setSSEHeaders(w)
 
 	// Get flusher after setting headers
-	flusher, ok := w.(http.Flusher)
+	sse, ok := newSSEWriter(w)
 	if !ok {
 		log.Printf("Streaming not supported by client")
 		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
 		return
 	}
 
-	// Get session from context
-	session, ok := utils.GetUserSessionFromContext(r)
-	if !ok {
-		log.Printf("Session not found in context")
-		http.Error(w, "Session not found", http.StatusUnauthorized)
-		return
+	// Tied to the request so that a client disconnect stops agents, tools and the LLM
+	ctx, cancel := context.WithCancel(r.Context())
+	streamingSession := h.startTurn(ctx, cancel, session, req.TokensPerSecond, run)
+
+	if err := h.streamEvents(sse, r, streamingSession, 0); err != nil {
+		log.Printf("[Chat] Stream %s ended early: %v", streamingSession.ID, err)
 	}
+}
 
-	// Parse request
+// parseChatRequest reads a chat request from the query string of a GET or the body
+// of a POST, which may be empty, and selects the requested account. On failure the error response has
+// already been written.
+func (h *ChatHandler) parseChatRequest(w http.ResponseWriter, r *http.Request, session *models.UserSession) (models.ChatRequest, bool) {
 	var req models.ChatRequest
 	if r.Method == http.MethodGet {
 		req = models.ChatRequest{
 			Message:   r.URL.Query().Get("message"),
 			Token:     r.URL.Query().Get("token"),
 			SessionID: r.URL.Query().Get("session_id"),
-			Stream:    r.URL.Query().Get("stream") == "true",
+			AccountID: r.URL.Query().Get("account_id"),
+		}
+		if stream, err := strconv.ParseBool(r.URL.Query().Get("stream")); err == nil {
+			req.Stream = &stream
+		}
+		if rate, err := strconv.ParseFloat(r.URL.Query().Get("tokens_per_second"), 64); err == nil {
+			req.TokensPerSecond = rate
 		}
 		log.Printf("[Request] Parsed GET parameters - Message: %s, Token: %s, SessionID: %s, Stream: %v",
-			req.Message, req.Token, req.SessionID, req.Stream)
+			req.Message, req.Token, req.SessionID, req.Streaming())
 	} else {
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
 			log.Printf("Error decoding request body: %v", err)
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			return
+			h.writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return req, false
 		}
 	}
 
-	if req.Message == "" {
-		http.Error(w, "Message is required", http.StatusBadRequest)
-		return
+	if req.TokensPerSecond < 0 {
+		h.writeJSONError(w, http.StatusBadRequest, "tokens_per_second must not be negative")
+		return req, false
 	}
+	if req.AccountID != "" {
+		if err := h.orchestrator.SelectAccount(session, req.AccountID); err != nil {
+			h.writeJSONError(w, http.StatusBadRequest, "Account not found")
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// processTurn answers a user message
+func (h *ChatHandler) processTurn(session *models.UserSession, message string) turnFunc {
+	return func(ctx context.Context, streamingSession *models.StreamingSession, emit services.ChatEventSink) (*services.TurnResult, error) {
+		return h.orchestrator.ProcessMessage(ctx, session, message, streamingSession, emit), nil
+	}
+}
+
+// turnFunc runs one turn of the orchestrator into a streaming session
+type turnFunc func(ctx context.Context, streamingSession *models.StreamingSession, emit services.ChatEventSink) (*services.TurnResult, error)
 
+// startTurn saves a new streaming session and runs the turn into it in the background,
+// smoothing the reply to tokensPerSecond when that is set. cancel is called once the
+// turn has finished, or when the stream is cancelled.
+func (h *ChatHandler) startTurn(ctx context.Context, cancel context.CancelFunc, session *models.UserSession, tokensPerSecond float64, run turnFunc) *models.StreamingSession {
 	log.Printf("[Session] Found session: %+v", session)
 	conversation := h.conversationService.GetOrCreateConversation(session.ID)
 	log.Printf("[Conversation] Using conversation: %+v", conversation)
 
-	// Create a unique streaming session ID
-	streamingSessionID := fmt.Sprintf("%s_%d", session.ID, time.Now().UnixNano())
-
-	// Create a streaming session
+	// Create a streaming session; its ID prefixes every event ID so clients can resume
 	streamingSession := models.NewStreamingSession(session.Token)
-	if streamingSession == nil {
-		log.Printf("Failed to create streaming session")
-		http.Error(w, "Failed to create streaming session", http.StatusInternalServerError)
-		return
-	}
-	streamingSession.ID = streamingSessionID
+	streamingSession.ID = fmt.Sprintf("%s_%d", session.ID, time.Now().UnixNano())
 
 	// Save streaming session
+	streamingSession.SetCancel(cancel)
+	streamingSession.SetPacing(tokensPerSecond)
 	h.sessionService.SaveStreamingSession(streamingSession)
+	streamingSession.RecordEvent(models.StreamEventStatus, map[string]string{
+		"session_id": streamingSession.ID,
+		"status":     "processing",
+	})
 
-	// Send initial status
-	if err := h.writeSSEData(w, fmt.Sprintf(`{"session_id": "%s", "status": "processing"}`, streamingSessionID)); err != nil {
-		log.Printf("Failed to send initial status: %v", err)
-		return
+	// Orchestration events are recorded in the streaming session next to the reply text,
+	// so that every connection, including a resumed one, sees them in the same order
+	emit := func(event services.ChatEvent) {
+		if err := streamingSession.RecordEvent(event.Type, event.Data); err != nil {
+			log.Printf("Error recording %s event: %v", event.Type, err)
+		}
 	}
-	flusher.Flush()
-
-	// Create a channel to track completion
-	done := make(chan bool)
 
 	// Process the message in a goroutine
 	go func() {
+		defer cancel()
+		// Readers must never wait on a stream nobody is writing to any more
+		defer streamingSession.MarkDone()
 		defer func() {
 			if r := recover(); r != nil {
 				log.Printf("Recovered from panic in message processing: %v", r)
-				h.writeSSEError(w, "Internal server error during message processing")
+				streamingSession.Fail("Internal server error during message processing")
 			}
-			close(done)
 		}()
 
-		// Detect intent
-		intent := h.intentService.RecognizeIntent(req.Message)
-		log.Printf("[Intent] Detected intent: %+v", intent)
-
-		// Add user message to conversation
-		err := h.conversationService.AddMessage(session.ID, "user", req.Message, intent.Name, nil, intent.Entities, "")
-		if err != nil {
-			log.Printf("[Message] Error adding user message: %v", err)
-			h.writeSSEError(w, fmt.Sprintf("Error adding message: %v", err))
-			return
+		if _, err := run(ctx, streamingSession, emit); err != nil {
+			_, message := turnError(err)
+			streamingSession.Fail(message)
 		}
+	}()
 
-		log.Printf("[Message] Received message from session %s: %s (Intent: %s, Confidence: %.2f)\n",
-			session.ID, req.Message, intent.Name, intent.Confidence)
+	return streamingSession
+}
 
-		// Build enhanced prompt with banking context
-		prompt := h.buildBankingPrompt(req.Message, conversation, intent)
-		if prompt == "" {
-			log.Printf("Failed to build prompt")
-			h.writeSSEError(w, "Failed to build prompt")
-			return
-		}
-		log.Printf("[Stream] Built prompt: %s", prompt)
+// resumeStream replays a stream after the event named by Last-Event-ID and follows it
+// to the end. Streams of other users, or that have expired, answer 204 so that
+// EventSource clients stop reconnecting.
+func (h *ChatHandler) resumeStream(w http.ResponseWriter, r *http.Request, session *models.UserSession, lastID string) {
+	streamingSessionID, after, ok := parseStreamEventID(lastID)
+	var streamingSession *models.StreamingSession
+	if ok {
+		streamingSession, ok = h.sessionService.GetStreamingSession(streamingSessionID)
+	}
+	if !ok || streamingSession.Token != session.Token {
+		log.Printf("[Chat] Cannot resume after %q", lastID)
+		h.setCORSHeaders(w)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 
-		// Query the Llama model with the request context
-		h.llamaService.QueryStreamingWithContext(context.Background(), prompt, streamingSession)
-	}()
+	h.setSSEHeaders(w)
+	sse, ok := newSSEWriter(w)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
 
-	// Stream the response
-	ticker := time.NewTicker(10 * time.Millisecond)
-	defer ticker.Stop()
-
-	var lastContent string
-	for {
-		select {
-		case <-done:
-			// Send final message
-			if err := h.writeSSEData(w, `{"response": "", "done": true}`); err != nil {
-				log.Printf("Error sending final message: %v", err)
-			}
-			flusher.Flush()
-			return
-		case <-ticker.C:
-			content, isDone := streamingSession.GetContentAndDone()
-			if content != lastContent {
-				// Only send if content has changed
-				if err := h.writeSSEData(w, fmt.Sprintf(`{"response": "%s", "done": false}`, content)); err != nil {
-					log.Printf("Error sending content update: %v", err)
-					return
-				}
-				flusher.Flush()
-				lastContent = content
-			}
-			if isDone {
-				// Send final message
-				if err := h.writeSSEData(w, `{"response": "", "done": true}`); err != nil {
-					log.Printf("Error sending final message: %v", err)
-				}
-				flusher.Flush()
-				return
+	log.Printf("[Chat] Resuming %s after event %d", streamingSession.ID, after)
+	if err := h.streamEvents(sse, r, streamingSession, after); err != nil {
+		log.Printf("[Chat] Resumed stream %s ended early: %v", streamingSession.ID, err)
+	}
+}
+
+// respondJSON handles a stream=false request synchronously and answers with a single ChatResponse
+func (h *ChatHandler) respondJSON(w http.ResponseWriter, r *http.Request, session *models.UserSession, run turnFunc) {
+	h.conversationService.GetOrCreateConversation(session.ID)
+
+	// Collects the reply for this request only; nobody polls it, so it is not saved
+	streamingSession := models.NewStreamingSession(session.Token)
+	streamingSession.ID = fmt.Sprintf("%s_%d", session.ID, time.Now().UnixNano())
+
+	var result *services.TurnResult
+	var err error
+	func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Printf("Recovered from panic in message processing: %v", rec)
+				result = nil
 			}
-		}
+		}()
+		result, err = run(r.Context(), streamingSession, func(services.ChatEvent) {})
+	}()
+
+	if r.Context().Err() != nil {
+		log.Printf("[Chat] Client disconnected before %s finished", streamingSession.ID)
+		return
 	}
+	if err != nil {
+		status, message := turnError(err)
+		h.writeJSONError(w, status, message)
+		return
+	}
+	if result == nil {
+		h.writeJSONError(w, http.StatusInternalServerError, "Internal server error during message processing")
+		return
+	}
+
+	content := streamingSession.GetContent()
+	h.setCORSHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result.ChatResponse(session.ID, content))
 }
 
-// splitIntoChunks splits a string into chunks of specified size
-func splitIntoChunks(s string, chunkSize int) []string {
-	var chunks []string
-	for i := 0; i < len(s); i += chunkSize {
-		end := i + chunkSize
-		if end > len(s) {
-			end = len(s)
-		}
-		chunks = append(chunks, s[i:end])
+// turnError maps an error of a chat turn to a status and a message for the client
+func turnError(err error) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrNothingToRegenerate):
+		return http.StatusConflict, "There is no reply to regenerate"
+	case errors.Is(err, services.ErrNothingToEdit):
+		return http.StatusConflict, "There is no message to edit"
+	case errors.Is(err, services.ErrTurnChangedData):
+		return http.StatusConflict, "The last message already changed your accounts and cannot be edited"
+	default:
+		return confirmationError(err)
 	}
-	return chunks
 }
 
-// handleStreamRequest handles SSE streaming for chat responses
-func (h *ChatHandler) handleStreamRequest(w http.ResponseWriter, r *http.Request) {
-	log.Printf("=== Stream request received from %s ===", r.RemoteAddr)
+// writeJSONError answers with {"error": message} and the given status
+func (h *ChatHandler) writeJSONError(w http.ResponseWriter, status int, message string) {
+	h.setCORSHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
 
-	// Set proper SSE headers
-	h.setSSEHeaders(w)
+// StreamEvents follows a stream started with Stream over SSE, from the start or after
+// the event named by Last-Event-ID
+func (h *ChatHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	log.Printf("=== Stream request received from %s ===", r.RemoteAddr)
 
 	// Get session from context
 	session, ok := utils.GetUserSessionFromContext(r)
 	if !ok {
 		log.Printf("Session not found in context")
-		h.writeSSEError(w, "Session not found")
+		h.writeJSONError(w, http.StatusUnauthorized, "Session not found")
 		return
 	}
 
-	// Get session ID from query parameters
-	sessionID := r.URL.Query().Get("session_id")
-	if sessionID == "" {
-		sessionID = session.ID
+	if lastID := lastEventID(r); lastID != "" {
+		h.resumeStream(w, r, session, lastID)
+		return
 	}
 
-	log.Printf("[Stream] Request for session ID: %s", sessionID)
+	streamID := mux.Vars(r)["streamId"]
+	log.Printf("[Stream] Request for stream ID: %s", streamID)
 
-	// Get streaming session
-	streamingSession, exists := h.sessionService.GetStreamingSession(sessionID)
-	if !exists {
-		log.Printf("[Stream] Streaming session not found: %s", sessionID)
-		h.writeSSEError(w, "Streaming session not found")
+	streamingSession, ok := h.ownedStream(session, streamID)
+	if !ok {
+		log.Printf("[Stream] Streaming session not found: %s", streamID)
+		h.writeJSONError(w, http.StatusNotFound, "Streaming session not found")
 		return
 	}
 
-	// Send initial connection message
-	h.writeSSEEvent(w, "open", "Connection established")
-	flusher, ok := w.(http.Flusher)
-	if ok {
-		flusher.Flush()
+	h.setSSEHeaders(w)
+	sse, ok := newSSEWriter(w)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
 	}
 
-	// Subscribe to the streaming session
-	contentChan := streamingSession.Subscribe()
-
-	// Create a ticker for keep-alive messages
-	keepAliveTicker := time.NewTicker(30 * time.Second)
-	defer keepAliveTicker.Stop()
-
-	// Create a done channel
-	done := make(chan struct{})
-	defer close(done)
-
-	// Check if the session is already done
-	if streamingSession.Done {
-		// Send the full content and done message
-		h.writeSSEEvent(w, "message", streamingSession.Content)
-		h.writeSSEEvent(w, "done", "[DONE]")
-
-		flusher.Flush()
+	if err := h.streamEvents(sse, r, streamingSession, 0); err != nil {
+		log.Printf("[Stream] Stream %s ended early: %v", streamingSession.ID, err)
+	}
+}
 
+// CancelStream stops the generation of a stream of the caller. Readers of the stream
+// receive a cancelled event followed by the done event.
+func (h *ChatHandler) CancelStream(w http.ResponseWriter, r *http.Request) {
+	session, ok := utils.GetUserSessionFromContext(r)
+	if !ok {
+		h.writeJSONError(w, http.StatusUnauthorized, "Session not found")
 		return
 	}
 
-	// Start streaming in a goroutine
-	go func() {
-		defer streamingSession.Unsubscribe(contentChan)
-
-		for {
-			select {
-			case content, ok := <-contentChan:
-				if !ok {
-					// Channel closed, session is done
-					h.writeSSEEvent(w, "done", "[DONE]")
-
-					flusher.Flush()
-					return
-				}
-
-				if content != "" {
-					// Send the content chunk
-					h.writeSSEEvent(w, "message", content)
-
-					flusher.Flush()
-
-				}
-
-			case <-keepAliveTicker.C:
-				// Send keep-alive message
-				w.Write([]byte(": keep-alive\n\n"))
-
-				flusher.Flush()
-
-			case <-r.Context().Done():
-				// Client disconnected
-				log.Printf("[Stream] Client disconnected")
-				return
-
-			case <-done:
-				return
-			}
-		}
-	}()
+	streamingSession, ok := h.ownedStream(session, mux.Vars(r)["streamId"])
+	if !ok {
+		h.writeJSONError(w, http.StatusNotFound, "Streaming session not found")
+		return
+	}
+	if !streamingSession.Cancel() {
+		h.writeJSONError(w, http.StatusConflict, "Stream has already finished")
+		return
+	}
+	log.Printf("[Stream] Cancelled %s", streamingSession.ID)
 
-	// Wait for the request context to be done (client disconnects)
-	<-r.Context().Done()
+	h.setCORSHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stream_id": streamingSession.ID,
+		"status":    "cancelled",
+	})
 }
 
-// This method is no longer used - replaced by handleStreamRequest
-func (h *ChatHandler) processMessageStream(w http.ResponseWriter, r *http.Request, req models.ChatRequest, session *models.UserSession) {
-	log.Printf("[DEPRECATED] processMessageStream called - this method is no longer used")
+// ownedStream looks up a streaming session of the caller. Sessions of other users
+// are reported as missing so that their IDs cannot be probed.
+func (h *ChatHandler) ownedStream(session *models.UserSession, streamID string) (*models.StreamingSession, bool) {
+	streamingSession, exists := h.sessionService.GetStreamingSession(streamID)
+	if !exists || streamingSession.Token != session.Token {
+		return nil, false
+	}
+	return streamingSession, true
 }
 
-func (h *ChatHandler) buildBankingPrompt(message string, conversation *models.Conversation, intent *services.Intent) string {
-	// Start with system context
-	prompt := "You are a helpful banking assistant. Provide clear, concise responses about banking services.\n\n"
-
-	// Add conversation history if available
-	if conversation != nil && len(conversation.Messages) > 0 {
-		prompt += "Previous conversation:\n"
-		for _, msg := range conversation.Messages {
-			prompt += fmt.Sprintf("%s: %s\n", msg.Role, msg.Content)
-		}
-		prompt += "\n"
+// clearWriteDeadline lifts the server's write timeout for a response that is meant to
+// stay open, such as an event stream or a long poll
+func clearWriteDeadline(w http.ResponseWriter) {
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("[Chat] Cannot clear the write deadline: %v", err)
 	}
-
-	// Add current message with intent context
-	prompt += fmt.Sprintf("User (Intent: %s): %s\nAssistant:", intent.Name, message)
-	return prompt
 }
 
 func (h *ChatHandler) setSSEHeaders(w http.ResponseWriter) {
+	clearWriteDeadline(w)
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -358,61 +449,112 @@ func (h *ChatHandler) setSSEHeaders(w http.ResponseWriter) {
 
 func (h *ChatHandler) setCORSHeaders(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID")
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
 	w.Header().Set("Access-Control-Max-Age", "86400")
 }
 
-func (h *ChatHandler) writeSSEData(w http.ResponseWriter, data string) error {
-	_, err := fmt.Fprintf(w, "data: %s\n\n", data)
-	return err
-}
+// Stream starts generating a reply without waiting for it. The client gets the
+// stream ID back and then follows the reply with StreamEvents or Poll, so a dropped
+// connection does not lose the generation.
+func (h *ChatHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		h.setCORSHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 
-func (h *ChatHandler) writeSSEError(w http.ResponseWriter, errorMsg string) {
-	fmt.Fprintf(w, "data: {\"error\":\"%s\"}\n\n", errorMsg)
-	if flusher, ok := w.(http.Flusher); ok {
-		flusher.Flush()
+	session, ok := utils.GetUserSessionFromContext(r)
+	if !ok {
+		h.writeJSONError(w, http.StatusUnauthorized, "Session not found")
+		return
 	}
-}
 
-func (h *ChatHandler) writeSSEEvent(w http.ResponseWriter, event string, data string) {
-	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
-}
+	req, ok := h.parseChatRequest(w, r, session)
+	if !ok {
+		return
+	}
+	if req.Message == "" {
+		h.writeJSONError(w, http.StatusBadRequest, "Message is required")
+		return
+	}
 
-// Keep existing Stream and Poll methods for backward compatibility
-func (h *ChatHandler) Stream(w http.ResponseWriter, r *http.Request) {
-	// Redirect to main ServeHTTP for consistency
-	h.ServeHTTP(w, r)
+	// Detached from the request, which ends as soon as the stream ID is returned
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), detachedStreamTimeout)
+	streamingSession := h.startTurn(ctx, cancel, session, req.TokensPerSecond, h.processTurn(session, req.Message))
+	log.Printf("[Stream] Started %s", streamingSession.ID)
+
+	h.setCORSHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stream_id":  streamingSession.ID,
+		"session_id": session.ID,
+		"status":     "processing",
+		"events_url": "/api/v1/chat/stream/" + streamingSession.ID,
+		"poll_url":   "/api/v1/chat/poll/" + streamingSession.ID,
+		"expires_at": streamingSession.ExpiresAt,
+	})
 }
 
+// Poll returns the reply text after the byte offset given by ?after=. When there is
+// none yet it waits up to ?wait= seconds for more, so clients can long-poll with the
+// returned offset.
 func (h *ChatHandler) Poll(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	path := strings.TrimPrefix(r.URL.Path, "/chat/poll/")
-	sessionID := path
-
-	// Get session from context for validation
-	_, ok := utils.GetUserSessionFromContext(r)
+	session, ok := utils.GetUserSessionFromContext(r)
 	if !ok {
-		http.Error(w, "Session not found in context", http.StatusUnauthorized)
+		h.writeJSONError(w, http.StatusUnauthorized, "Session not found")
 		return
 	}
 
-	session, exists := h.sessionService.GetStreamingSession(sessionID)
-	if !exists {
-		http.Error(w, "Streaming session not found", http.StatusNotFound)
+	after, err := queryInt(r, "after", 0)
+	if err != nil || after < 0 {
+		h.writeJSONError(w, http.StatusBadRequest, "after must be a non-negative byte offset")
 		return
 	}
+	waitSeconds, err := queryInt(r, "wait", int(defaultPollWait.Seconds()))
+	if err != nil || waitSeconds < 0 {
+		h.writeJSONError(w, http.StatusBadRequest, "wait must be a non-negative number of seconds")
+		return
+	}
+	wait := min(time.Duration(waitSeconds)*time.Second, maxPollWait)
+
+	streamingSession, ok := h.ownedStream(session, mux.Vars(r)["sessionId"])
+	if !ok {
+		h.writeJSONError(w, http.StatusNotFound, "Streaming session not found")
+		return
+	}
+	streamingSession.UpdateLastPoll()
 
-	content, done := session.GetContentAndDone()
+	clearWriteDeadline(w)
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	content, next, done, err := streamingSession.WaitContent(ctx, after)
+	if err != nil && r.Context().Err() != nil {
+		return
+	}
 
+	h.setCORSHeaders(w)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"content": content,
-		"done":    done,
+		"stream_id": streamingSession.ID,
+		"content":   content,
+		"offset":    next,
+		"done":      done,
 	})
 }
+
+// queryInt reads an integer query parameter, falling back to def when it is absent
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
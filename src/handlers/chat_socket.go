@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/banking/ai-agents-banking/src/models"
+	"github.com/banking/ai-agents-banking/src/services"
+	"github.com/banking/ai-agents-banking/src/utils"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait is how long a frame may take to write; slower clients are disconnected
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long the connection may stay silent before it is considered dead
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod must be shorter than wsPongWait so that pongs arrive in time
+	wsPingPeriod = (wsPongWait * 9) / 10
+	// wsMaxFrameSize bounds a single client frame
+	wsMaxFrameSize = 64 << 10
+	// wsSendBuffer is how many frames may queue for a connection before producers wait
+	wsSendBuffer = 64
+)
+
+// Frame types sent by the client. Everything the server sends is a stream event
+// type, or pong, declined or error.
+const (
+	wsFrameMessage    = "message"
+	wsFrameRegenerate = "regenerate"
+	wsFrameEdit       = "edit"
+	wsFrameCancel     = "cancel"
+	wsFrameConfirm    = "confirm"
+	wsFrameDecline    = "decline"
+	wsFrameResume     = "resume"
+	wsFramePing       = "ping"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	// Connections are authenticated by token rather than cookies, so any origin may
+	// connect, as with the CORS policy of the REST endpoints
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsClientFrame is a frame received from the client
+type wsClientFrame struct {
+	Type           string `json:"type"`
+	Message        string `json:"message,omitempty"`
+	AccountID      string `json:"account_id,omitempty"`
+	ConfirmationID string `json:"confirmation_id,omitempty"`
+	StreamID       string `json:"stream_id,omitempty"`
+	After          int64  `json:"after,omitempty"`
+	// TokensPerSecond smooths the reply of the turn the frame starts; 0 keeps the server setting
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+}
+
+// wsServerFrame is a frame sent to the client. Stream events carry their stream and
+// event IDs so that a client which reconnects can resume after the last one it saw.
+type wsServerFrame struct {
+	Type     string          `json:"type"`
+	StreamID string          `json:"stream_id,omitempty"`
+	EventID  int64           `json:"event_id,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// chatSocket is one WebSocket connection. At most one turn runs at a time; its events
+// are pumped from the streaming session into a bounded send queue drained by a single
+// writer, so a slow client holds back its own stream and nobody else's.
+type chatSocket struct {
+	handler *ChatHandler
+	conn    *websocket.Conn
+	session *models.UserSession
+	ctx     context.Context // Ends when the connection closes
+	send    chan wsServerFrame
+
+	mu     sync.Mutex
+	active *models.StreamingSession
+}
+
+// ServeWS upgrades an authenticated request to a WebSocket carrying chat in both
+// directions: user messages, confirmations and cancellation in, stream events out
+func (h *ChatHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	session, ok := utils.GetUserSessionFromContext(r)
+	if !ok {
+		h.writeJSONError(w, http.StatusUnauthorized, "Session not found")
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already answered the request
+		log.Printf("[WS] Upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	socket := &chatSocket{
+		handler: h,
+		conn:    conn,
+		session: session,
+		ctx:     ctx,
+		send:    make(chan wsServerFrame, wsSendBuffer),
+	}
+	log.Printf("[WS] Connected session %s", session.ID)
+
+	go socket.writeLoop()
+	socket.readLoop()
+	log.Printf("[WS] Disconnected session %s", session.ID)
+}
+
+// readLoop handles client frames until the connection fails or goes quiet
+func (c *chatSocket) readLoop() {
+	c.conn.SetReadLimit(wsMaxFrameSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("[WS] Read error: %v", err)
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var frame wsClientFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			c.fail("Invalid frame")
+			continue
+		}
+		c.handle(frame)
+	}
+}
+
+// writeLoop is the only writer of the connection. It drains the send queue and pings
+// the client; a write that misses its deadline closes the connection.
+func (c *chatSocket) writeLoop() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteWait))
+			return
+		case frame := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteJSON(frame); err != nil {
+				log.Printf("[WS] Write error: %v", err)
+				c.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// push queues a frame, waiting while the queue is full. It reports false once the
+// connection has closed.
+func (c *chatSocket) push(frame wsServerFrame) bool {
+	select {
+	case c.send <- frame:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+func (c *chatSocket) fail(message string) {
+	c.push(wsServerFrame{Type: models.StreamEventError, Error: message})
+}
+
+func (c *chatSocket) handle(frame wsClientFrame) {
+	if frame.TokensPerSecond < 0 {
+		c.fail("tokens_per_second must not be negative")
+		return
+	}
+
+	switch frame.Type {
+	case wsFrameMessage:
+		if frame.Message == "" {
+			c.fail("Message is required")
+			return
+		}
+		if frame.AccountID != "" {
+			if err := c.handler.orchestrator.SelectAccount(c.session, frame.AccountID); err != nil {
+				c.fail("Account not found")
+				return
+			}
+		}
+		c.startTurn(frame.TokensPerSecond, c.handler.processTurn(c.session, frame.Message))
+
+	case wsFrameRegenerate:
+		c.startTurn(frame.TokensPerSecond, func(ctx context.Context, streamingSession *models.StreamingSession, emit services.ChatEventSink) (*services.TurnResult, error) {
+			return c.handler.orchestrator.Regenerate(ctx, c.session, streamingSession, emit)
+		})
+
+	case wsFrameEdit:
+		if frame.Message == "" {
+			c.fail("Message is required")
+			return
+		}
+		c.startTurn(frame.TokensPerSecond, func(ctx context.Context, streamingSession *models.StreamingSession, emit services.ChatEventSink) (*services.TurnResult, error) {
+			return c.handler.orchestrator.EditLastMessage(ctx, c.session, frame.Message, streamingSession, emit)
+		})
+
+	case wsFrameConfirm:
+		c.startTurn(frame.TokensPerSecond, func(ctx context.Context, streamingSession *models.StreamingSession, emit services.ChatEventSink) (*services.TurnResult, error) {
+			return c.handler.orchestrator.ConfirmAction(ctx, c.session, frame.ConfirmationID, streamingSession, emit)
+		})
+
+	case wsFrameDecline:
+		if _, err := c.handler.orchestrator.CancelAction(c.session, frame.ConfirmationID); err != nil {
+			_, message := confirmationError(err)
+			c.fail(message)
+			return
+		}
+		data, _ := json.Marshal(map[string]string{"confirmation_id": frame.ConfirmationID, "status": "cancelled"})
+		c.push(wsServerFrame{Type: "declined", Data: data})
+
+	case wsFrameCancel:
+		c.cancelTurn(frame.StreamID)
+
+	case wsFrameResume:
+		streamingSession, ok := c.handler.ownedStream(c.session, frame.StreamID)
+		if !ok {
+			c.fail("Streaming session not found")
+			return
+		}
+		go c.follow(streamingSession, frame.After)
+
+	case wsFramePing:
+		c.push(wsServerFrame{Type: "pong"})
+
+	default:
+		c.fail("Unknown frame type " + frame.Type)
+	}
+}
+
+// startTurn runs a turn unless one is still streaming. The turn outlives the
+// connection, up to detachedStreamTimeout, so that a client can resume it.
+func (c *chatSocket) startTurn(tokensPerSecond float64, run turnFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.active != nil && !c.active.IsDone() {
+		c.fail("A reply is still streaming; cancel it first")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.ctx), detachedStreamTimeout)
+	c.active = c.handler.startTurn(ctx, cancel, c.session, tokensPerSecond, run)
+	go c.follow(c.active, 0)
+}
+
+// cancelTurn stops the turn streaming into the given stream of the caller, or the
+// active turn when no stream is named. Followers of the stream see it cancelled.
+func (c *chatSocket) cancelTurn(streamID string) {
+	c.mu.Lock()
+	target := c.active
+	c.mu.Unlock()
+
+	if streamID != "" {
+		streamingSession, ok := c.handler.ownedStream(c.session, streamID)
+		if !ok {
+			c.fail("Streaming session not found")
+			return
+		}
+		target = streamingSession
+	}
+
+	if target == nil || !target.Cancel() {
+		c.fail("No reply is streaming")
+		return
+	}
+	log.Printf("[WS] Cancelled %s", target.ID)
+}
+
+// follow pumps the events of a streaming session after the given event into the
+// send queue until the stream is done or the connection closes
+func (c *chatSocket) follow(streamingSession *models.StreamingSession, after int64) {
+	cursor := streamingSession.Subscribe(after)
+	for {
+		events, err := cursor.Next(c.ctx)
+		if err != nil {
+			return
+		}
+		for _, event := range events {
+			if !c.push(wsServerFrame{
+				Type:     event.Type,
+				StreamID: streamingSession.ID,
+				EventID:  event.ID,
+				Data:     event.Data,
+			}) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/banking/ai-agents-banking/src/models"
+	"github.com/banking/ai-agents-banking/src/services"
+	"github.com/banking/ai-agents-banking/src/utils"
+	"github.com/gorilla/mux"
+)
+
+type ConfirmationHandler struct {
+	orchestrator *services.ChatOrchestrator
+}
+
+func NewConfirmationHandler(orchestrator *services.ChatOrchestrator) *ConfirmationHandler {
+	return &ConfirmationHandler{
+		orchestrator: orchestrator,
+	}
+}
+
+// GetPending returns the action of the current session that is waiting for confirmation
+func (h *ConfirmationHandler) GetPending(w http.ResponseWriter, r *http.Request) {
+	session, ok := utils.GetUserSessionFromContext(r)
+	if !ok {
+		http.Error(w, `{"error": "Session not found in context"}`, http.StatusUnauthorized)
+		return
+	}
+
+	action, err := h.orchestrator.GetPendingAction(session)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(action)
+}
+
+// Confirm executes the pending action and returns the assistant reply
+func (h *ConfirmationHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	session, ok := utils.GetUserSessionFromContext(r)
+	if !ok {
+		http.Error(w, `{"error": "Session not found in context"}`, http.StatusUnauthorized)
+		return
+	}
+
+	confirmationID := mux.Vars(r)["confirmationId"]
+	streamingSession := models.NewStreamingSession(session.Token)
+	emit := func(services.ChatEvent) {}
+
+	result, err := h.orchestrator.ConfirmAction(r.Context(), session, confirmationID, streamingSession, emit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"confirmation_id": confirmationID,
+		"status":          "confirmed",
+		"agent":           result.Response.AgentName,
+		"response":        streamingSession.GetContent(),
+		"data":            result.Response.Data,
+		"tool_calls":      result.ToolCalls,
+	})
+}
+
+// Cancel discards the pending action without executing it
+func (h *ConfirmationHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	session, ok := utils.GetUserSessionFromContext(r)
+	if !ok {
+		http.Error(w, `{"error": "Session not found in context"}`, http.StatusUnauthorized)
+		return
+	}
+
+	confirmationID := mux.Vars(r)["confirmationId"]
+	if _, err := h.orchestrator.CancelAction(session, confirmationID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"confirmation_id": confirmationID,
+		"status":          "cancelled",
+	})
+}
+
+func (h *ConfirmationHandler) writeError(w http.ResponseWriter, err error) {
+	status, message := confirmationError(err)
+	http.Error(w, fmt.Sprintf(`{"error": %q}`, message), status)
+}
+
+// confirmationError maps an error of a confirmation to a status and a message for the client
+func confirmationError(err error) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrConfirmationExpired):
+		return http.StatusGone, "Confirmation has expired"
+	case errors.Is(err, services.ErrConfirmationNotFound):
+		return http.StatusNotFound, "Confirmation not found"
+	default:
+		return http.StatusInternalServerError, "Failed to process confirmation"
+	}
+}
@@ -13,17 +13,20 @@ type HealthHandler struct {
 	agentService        *services.AgentService
 	conversationService *services.ConversationService
 	sessionService      *services.SessionService
+	llm                 *services.ResilientProvider
 }
 
 func NewHealthHandler(
 	agentService *services.AgentService,
 	conversationService *services.ConversationService,
 	sessionService *services.SessionService,
+	llm *services.ResilientProvider,
 ) *HealthHandler {
 	return &HealthHandler{
 		agentService:        agentService,
 		conversationService: conversationService,
 		sessionService:      sessionService,
+		llm:                 llm,
 	}
 }
 
@@ -32,11 +35,18 @@ func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 
+	// Without the model replies are the agents' own messages, so the service is degraded rather than down
+	health := "OK"
+	if !h.llm.Healthy() {
+		health = "DEGRADED"
+	}
+
 	status := map[string]interface{}{
-		"status":    "OK",
+		"status":    health,
 		"timestamp": time.Now(),
 		"version":   "1.0.0",
 		"service":   "Banking Agents API",
+		"llm":       h.llm.Status(),
 		"metrics": map[string]interface{}{
 			"agents":          h.agentService.GetAgentsCount(),
 			"active_sessions": h.sessionService.GetActiveSessionsCount(),
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/banking/ai-agents-banking/src/services"
+)
+
+// IntentsHandler lets conversation designers inspect the intent catalog in use
+type IntentsHandler struct {
+	intents *services.IntentCatalog
+}
+
+func NewIntentsHandler(intents *services.IntentCatalog) *IntentsHandler {
+	return &IntentsHandler{intents: intents}
+}
+
+// ServeHTTP describes the loaded catalog and lists its intents. With a message query
+// parameter it also shows the intent and entities recognised in that message.
+func (h *IntentsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"catalog": h.intents.Info(),
+		"intents": h.intents.Definitions(),
+	}
+	if message := r.URL.Query().Get("message"); message != "" {
+		intent := h.intents.RecognizeIntent(message)
+		agent, _ := h.intents.AgentFor(intent.Name)
+		response["recognized"] = map[string]interface{}{
+			"message":    message,
+			"intent":     intent.Name,
+			"confidence": intent.Confidence,
+			"entities":   intent.Entities,
+			"agent":      agent,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
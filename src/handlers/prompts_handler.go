@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/banking/ai-agents-banking/src/services"
+)
+
+// PromptsHandler lets conversation designers inspect and reload the prompt templates
+type PromptsHandler struct {
+	prompts *services.PromptStore
+}
+
+func NewPromptsHandler(prompts *services.PromptStore) *PromptsHandler {
+	return &PromptsHandler{prompts: prompts}
+}
+
+// ServeHTTP describes the prompt templates in use
+func (h *PromptsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.prompts.Info())
+}
+
+// Reload parses the prompt templates again. When any template fails to parse the
+// templates in use are kept and the error is returned.
+func (h *PromptsHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	info, err := h.prompts.Reload()
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		log.Printf("[Prompts] Reload failed: %v", err)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   err.Error(),
+			"current": h.prompts.Info(),
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(info)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+const (
+	// sseRetry is the reconnection delay suggested to clients
+	sseRetry = 3 * time.Second
+	// sseHeartbeat is how often an idle stream sends a comment to keep proxies from closing it
+	sseHeartbeat = 15 * time.Second
+)
+
+// sseWriter writes Server-Sent Events frames as defined by the HTML Living Standard
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	return &sseWriter{w: w, flusher: flusher}, true
+}
+
+// Retry tells the client how long to wait before reconnecting
+func (s *sseWriter) Retry(delay time.Duration) error {
+	_, err := fmt.Fprintf(s.w, "retry: %d\n\n", delay.Milliseconds())
+	s.flusher.Flush()
+	return err
+}
+
+// Event writes one event. Payloads spanning several lines are split over several
+// data fields, which the client joins back with newlines.
+func (s *sseWriter) Event(id string, eventType string, data []byte) error {
+	var frame strings.Builder
+	if id != "" {
+		frame.WriteString("id: " + id + "\n")
+	}
+	if eventType != "" {
+		frame.WriteString("event: " + eventType + "\n")
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		frame.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	frame.WriteString("\n")
+
+	_, err := s.w.Write([]byte(frame.String()))
+	s.flusher.Flush()
+	return err
+}
+
+// Heartbeat writes a comment line, which clients ignore
+func (s *sseWriter) Heartbeat() error {
+	_, err := s.w.Write([]byte(": heartbeat\n\n"))
+	s.flusher.Flush()
+	return err
+}
+
+// streamEventID identifies an event across reconnections as "<streaming session ID>:<event number>"
+func streamEventID(streamingSessionID string, id int64) string {
+	return fmt.Sprintf("%s:%d", streamingSessionID, id)
+}
+
+// parseStreamEventID splits a Last-Event-ID into the streaming session ID and event number
+func parseStreamEventID(lastEventID string) (string, int64, bool) {
+	separator := strings.LastIndex(lastEventID, ":")
+	if separator <= 0 {
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(lastEventID[separator+1:], 10, 64)
+	if err != nil || id < 0 {
+		return "", 0, false
+	}
+	return lastEventID[:separator], id, true
+}
+
+// lastEventID reads the Last-Event-ID header, or the last_event_id query parameter
+// for clients that cannot set headers
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("last_event_id")
+}
+
+// streamEvents sends the events recorded in the streaming session after the event
+// numbered after, then follows the session until it is done or the client leaves
+func (h *ChatHandler) streamEvents(sse *sseWriter, r *http.Request, streamingSession *models.StreamingSession, after int64) error {
+	if err := sse.Retry(sseRetry); err != nil {
+		return err
+	}
+
+	cursor := streamingSession.Subscribe(after)
+	for {
+		ctx, cancel := context.WithTimeout(r.Context(), sseHeartbeat)
+		events, err := cursor.Next(ctx)
+		cancel()
+
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case errors.Is(err, context.DeadlineExceeded) && r.Context().Err() == nil:
+			if err := sse.Heartbeat(); err != nil {
+				return err
+			}
+			continue
+		case err != nil:
+			return err
+		}
+
+		for _, event := range events {
+			if err := sse.Event(streamEventID(streamingSession.ID, event.ID), event.Type, event.Data); err != nil {
+				return err
+			}
+		}
+	}
+}
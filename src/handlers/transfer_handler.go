@@ -6,6 +6,8 @@ import (
 
 	"github.com/banking/ai-agents-banking/src/dao"
 	"github.com/banking/ai-agents-banking/src/models"
+	"github.com/banking/ai-agents-banking/src/utils"
+	"github.com/gorilla/mux"
 )
 
 type TransferHandler struct {
@@ -21,8 +23,8 @@ func NewTransferHandler(transferDAO *dao.TransferDAO, accountDAO *dao.AccountDAO
 }
 
 func (h *TransferHandler) ListTransfers(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
-	if userID == "" {
+	userID, ok := utils.GetUserIDFromRequest(r)
+	if !ok || userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -38,8 +40,8 @@ func (h *TransferHandler) ListTransfers(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *TransferHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
-	if userID == "" {
+	userID, ok := utils.GetUserIDFromRequest(r)
+	if !ok || userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -90,13 +92,16 @@ func (h *TransferHandler) CreateTransfer(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *TransferHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
-	if userID == "" {
+	userID, ok := utils.GetUserIDFromRequest(r)
+	if !ok || userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	transferID := r.URL.Query().Get("id")
+	transferID := mux.Vars(r)["transferId"]
+	if transferID == "" {
+		transferID = r.URL.Query().Get("id")
+	}
 	if transferID == "" {
 		http.Error(w, "Transfer ID required", http.StatusBadRequest)
 		return
@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"bufio"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"time"
 
@@ -79,7 +82,17 @@ func (rw *loggingResponseWriter) Flush() {
 	}
 }
 
-// Optional: implement Hijacker, Pusher, ReaderFrom if needed in future
+// Preserve Hijacker for WebSocket upgrades
+func (rw *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	rw.statusCode = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Optional: implement Pusher, ReaderFrom if needed in future
 
 func getStatusText(code int) string {
 	switch {
@@ -95,3 +108,8 @@ func getStatusText(code int) string {
 		return "UNKNOWN"
 	}
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to lift deadlines
+func (rw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
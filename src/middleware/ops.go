@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// OpsTokenHeader carries the operator token on operational requests
+const OpsTokenHeader = "X-Ops-Token"
+
+// OpsMiddleware admits only requests that carry the operator token. Customer sessions
+// never grant access to operational endpoints.
+type OpsMiddleware struct {
+	token string
+}
+
+func NewOpsMiddleware(token string) *OpsMiddleware {
+	return &OpsMiddleware{token: token}
+}
+
+// MiddlewareFunc returns a Gorilla Mux compatible middleware function
+func (m *OpsMiddleware) MiddlewareFunc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := r.Header.Get(OpsTokenHeader)
+		if m.token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(m.token)) != 1 {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error": "Operator token required", "code": "FORBIDDEN"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
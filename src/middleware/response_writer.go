@@ -36,3 +36,8 @@ func (rw *ResponseWriter) Flush() {
 		flusher.Flush()
 	}
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to lift deadlines
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
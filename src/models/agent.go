@@ -3,6 +3,7 @@ package models
 type AgentContext struct {
 	SessionID    string
 	UserID       string
+	AccountID    string // Selected account; empty means the user's primary account
 	Message      string
 	Intent       string
 	Entities     map[string]interface{}
@@ -10,18 +11,47 @@ type AgentContext struct {
 	Conversation *Conversation
 	CurrentStep  *ConversationStep
 	Confidence   float64
+	Confirmed    bool // Set only when replaying a pending action the user confirmed
+}
+
+// Principal returns the identity the context's tool calls run under
+func (c *AgentContext) Principal() Principal {
+	return Principal{UserID: c.UserID, SessionID: c.SessionID, AccountID: c.AccountID}
 }
 
 type AgentResponse struct {
-	Message           string
-	AgentName         string
-	Data              interface{}
-	Actions           []string
-	RequiresInput     bool
-	RequiresTool      bool
-	ToolName          string
-	ToolParams        map[string]interface{}
-	MissingParameters []string
+	Message              string
+	AgentName            string
+	Data                 interface{}
+	Actions              []string
+	RequiresInput        bool
+	RequiresTool         bool
+	RequiresConfirmation bool
+	ToolName             string
+	ToolParams           map[string]interface{}
+	MissingParameters    []string
+	Ranking              []AgentScore // How the router scored the agents for this message
+	Clarify              bool         // The request matched several agents about equally; Message asks which was meant
+	Handoff              *Handoff     // Another agent the user was offered to continue with; Message asks
+}
+
+// Handoff offers to continue a request with another agent, such as adding a payee a
+// transfer could not find. The user takes it up by answering yes.
+type Handoff struct {
+	Agent      string                 `json:"agent"`
+	Intent     string                 `json:"intent"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"` // The new step starts with these
+}
+
+// AgentScore is one agent's standing when a message is routed
+type AgentScore struct {
+	Agent        string   `json:"agent"`
+	Score        float64  `json:"score"`
+	IntentMatch  bool     `json:"intent_match"`
+	KeywordScore float64  `json:"keyword_score"`
+	Keywords     []string `json:"keywords,omitempty"`
+	Sticky       bool     `json:"sticky"`
+	Confidence   float64  `json:"confidence"`
 }
 
 type Conversation struct {
@@ -31,12 +61,20 @@ type Conversation struct {
 }
 
 type Message struct {
+	ID        string
 	Role      string
 	Content   string
 	Intent    string
 	Actions   []string
 	Entities  map[string]interface{}
 	AgentName string
+	// Response is the agent result an assistant reply was phrased from, kept so the
+	// reply can be regenerated without running the agent again
+	Response *AgentResponse
+	// ChangedData marks an assistant reply whose turn ran an operation with side effects
+	ChangedData bool
+	// PromptVersion identifies the prompt templates an assistant reply was phrased with
+	PromptVersion string
 }
 
 type ConversationStep struct {
@@ -46,13 +84,33 @@ type ConversationStep struct {
 	Missing    []string
 	Complete   bool
 	AgentName  string
+	Handoff    *Handoff // Offered by the agent's last question
+}
+
+// Clone returns a copy of the step that later merges into the original do not affect
+func (s *ConversationStep) Clone() *ConversationStep {
+	if s == nil {
+		return nil
+	}
+	clone := *s
+	clone.Parameters = make(map[string]interface{}, len(s.Parameters))
+	for key, value := range s.Parameters {
+		clone.Parameters[key] = value
+	}
+	clone.Missing = append([]string(nil), s.Missing...)
+	return &clone
 }
 
 type StreamingContext struct {
-	Message      string
-	Intent       *Intent
-	Conversation *Conversation
-	Session      *UserSession
+	Message       string
+	Intent        *Intent
+	Conversation  *Conversation // Turns the summary does not cover
+	Summary       string        // Rolling summary of the older turns
+	PendingStep   *ConversationStep
+	Accounts      []Account // The customer's accounts, for the prompt templates
+	AgentName     string    // Agent handling the message, which picks the prompt template
+	Session       *UserSession
+	AgentResponse *AgentResponse
 }
 
 type Intent struct {
@@ -62,13 +120,16 @@ type Intent struct {
 }
 
 type ChatResponse struct {
-	Response    string
-	SessionID   string
-	Intent      string
-	Confidence  float64
-	Tools       []string
-	AgentUsed   string
-	AgentData   interface{}
-	NextActions []string
-	Entities    map[string]interface{}
+	Response          string                 `json:"response"`
+	SessionID         string                 `json:"session_id"`
+	Intent            string                 `json:"intent"`
+	Confidence        float64                `json:"confidence"`
+	Tools             []string               `json:"tools"`
+	AgentUsed         string                 `json:"agent_used"`
+	AgentData         interface{}            `json:"agent_data,omitempty"`
+	NextActions       []string               `json:"next_actions"`
+	Entities          map[string]interface{} `json:"entities"`
+	RequiresInput     bool                   `json:"requires_input"`
+	MissingParameters []string               `json:"missing_parameters,omitempty"`
+	Confirmation      *PendingAction         `json:"confirmation,omitempty"` // Set when the action waits for the user's confirmation
 }
@@ -16,10 +16,3 @@ type ConversationHistory struct {
 	CreatedAt    int64                 `json:"created_at"`
 	LastAccessed int64                 `json:"last_accessed"`
 }
-
-type IntentPattern struct {
-	Name     string   `json:"name"`
-	Patterns []string `json:"patterns"`
-	Keywords []string `json:"keywords"`
-	Action   string   `json:"action"`
-}
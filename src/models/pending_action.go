@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// PendingAction is a high-risk operation waiting for the user's explicit confirmation
+type PendingAction struct {
+	ID         string                 `json:"confirmation_id"`
+	SessionID  string                 `json:"session_id"`
+	UserID     string                 `json:"user_id"`
+	AccountID  string                 `json:"account_id,omitempty"` // Account selected when the action was requested
+	AgentName  string                 `json:"agent_name,omitempty"`
+	Intent     string                 `json:"intent,omitempty"`
+	ToolName   string                 `json:"tool"`
+	Parameters map[string]interface{} `json:"-"` // Agent parameters replayed on confirmation
+	ToolParams map[string]interface{} `json:"params"`
+	Summary    string                 `json:"summary"`
+	CreatedAt  time.Time              `json:"created_at"`
+	ExpiresAt  time.Time              `json:"expires_at"`
+}
+
+func (a *PendingAction) IsExpired() bool {
+	return time.Now().After(a.ExpiresAt)
+}
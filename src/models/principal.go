@@ -0,0 +1,9 @@
+package models
+
+// Principal identifies on whose behalf a tool runs. Tools must only read and
+// change data that belongs to UserID.
+type Principal struct {
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	AccountID string `json:"account_id,omitempty"` // Selected account; empty means the user's primary account
+}
@@ -1,10 +1,17 @@
 package models
 
 type ChatRequest struct {
-	Message   string `json:"message"`
-	SessionID string `json:"session_id,omitempty"`
-	Token     string `json:"token,omitempty"`
-	Stream    bool   `json:"stream,omitempty"`
+	Message         string  `json:"message"`
+	SessionID       string  `json:"session_id,omitempty"`
+	Token           string  `json:"token,omitempty"`
+	Stream          *bool   `json:"stream,omitempty"`            // false asks for a single JSON ChatResponse; unset or true streams SSE
+	AccountID       string  `json:"account_id,omitempty"`        // Selects the account operations default to
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"` // Smooths the streamed reply to this rate; 0 keeps the server setting
+}
+
+// Streaming reports whether the reply should be streamed; requests that do not say are streamed
+func (r ChatRequest) Streaming() bool {
+	return r.Stream == nil || *r.Stream
 }
 
 type LlamaRequest struct {
@@ -0,0 +1,21 @@
+package models
+
+import "encoding/json"
+
+// Stream event types recorded by the streaming session itself. Orchestration
+// events (agent, tool_call, tool_result, confirmation, ...) are recorded alongside them.
+const (
+	StreamEventStatus    = "status"
+	StreamEventToken     = "token"
+	StreamEventError     = "error"
+	StreamEventCancelled = "cancelled"
+	StreamEventDone      = "done"
+)
+
+// StreamEvent is one event of a streamed reply. Events are numbered from 1 in the
+// order they were recorded, so a reconnecting client can resume after the last one it saw.
+type StreamEvent struct {
+	ID   int64           `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
@@ -0,0 +1,359 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// MaxStreamBytes bounds the memory a single streamed reply may hold. A reply that
+// grows past it is closed with an error event instead of growing without limit.
+const MaxStreamBytes = 4 << 20
+
+// streamEventOverhead approximates the memory an event needs besides its payload
+const streamEventOverhead = 64
+
+// ErrStreamClosed is returned when recording into a stream that is already done
+var ErrStreamClosed = errors.New("stream is closed")
+
+// StreamingSession holds one streamed reply as an append-only log. The reply text is
+// addressed by byte offset and the events by ID; readers keep their own position and
+// wait on a condition variable, so no reader can miss or steal what another reads.
+type StreamingSession struct {
+	ID        string    `json:"id"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+	LastPoll  time.Time `json:"last_poll"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	done    bool
+	content []byte
+	events  []StreamEvent // Everything sent to clients, kept for Last-Event-ID resume
+	size    int           // Bytes held by content and events, bounded by MaxStreamBytes
+	cancel  context.CancelFunc
+	pacing  float64 // Tokens per second the reply is smoothed to; 0 passes tokens through
+}
+
+// NewStreamingSession creates a new streaming session
+func NewStreamingSession(token string) *StreamingSession {
+	now := time.Now()
+	s := &StreamingSession{
+		ID:        generateSessionID(),
+		Token:     token,
+		CreatedAt: now,
+		LastPoll:  now,
+		ExpiresAt: now.Add(30 * time.Minute),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// AppendContent adds reply text and records it as a token event. Text arriving
+// after the session is done is ignored.
+func (s *StreamingSession) AppendContent(content string) {
+	if content == "" {
+		return
+	}
+	data, err := json.Marshal(map[string]string{"response": content})
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	if !s.reserve(len(content) + len(data)) {
+		return
+	}
+
+	s.content = append(s.content, content...)
+	s.LastPoll = time.Now()
+	s.recordEvent(StreamEventToken, data)
+	s.cond.Broadcast()
+}
+
+// RecordEvent appends an event with a JSON-encoded payload
+func (s *StreamingSession) RecordEvent(eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return ErrStreamClosed
+	}
+	if !s.reserve(len(data)) {
+		return ErrStreamClosed
+	}
+	s.recordEvent(eventType, data)
+	s.cond.Broadcast()
+	return nil
+}
+
+// reserve accounts for size more bytes. When the limit would be exceeded the stream
+// is closed with an error event and false is returned. Callers hold s.mu.
+func (s *StreamingSession) reserve(size int) bool {
+	if s.size+size+streamEventOverhead <= MaxStreamBytes {
+		s.size += size + streamEventOverhead
+		return true
+	}
+	s.recordEvent(StreamEventError, json.RawMessage(`{"error":"The reply is too long to stream"}`))
+	s.close()
+	return false
+}
+
+// recordEvent appends an already encoded event; callers hold s.mu
+func (s *StreamingSession) recordEvent(eventType string, data json.RawMessage) {
+	s.events = append(s.events, StreamEvent{
+		ID:   int64(len(s.events)) + 1,
+		Type: eventType,
+		Data: data,
+	})
+}
+
+// MarkDone completes the stream with a done event and wakes every waiting reader.
+// Calling it again has no effect.
+func (s *StreamingSession) MarkDone() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	s.close()
+}
+
+// Fail completes the stream with an error event followed by the done event
+func (s *StreamingSession) Fail(message string) {
+	data, _ := json.Marshal(map[string]string{"error": message})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	s.recordEvent(StreamEventError, data)
+	s.close()
+}
+
+// SetCancel registers how to stop the generation writing into the session
+func (s *StreamingSession) SetCancel(cancel context.CancelFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancel = cancel
+}
+
+// SetPacing asks for the reply to be smoothed to the given number of tokens per
+// second; 0 leaves it to the server
+func (s *StreamingSession) SetPacing(tokensPerSecond float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pacing = tokensPerSecond
+}
+
+// Pacing returns the tokens per second asked for with SetPacing
+func (s *StreamingSession) Pacing() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pacing
+}
+
+// Cancel stops the generation and completes the stream with a cancelled event
+// followed by the done event. It reports false when the stream was already done.
+func (s *StreamingSession) Cancel() bool {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return false
+	}
+	cancel := s.cancel
+	s.recordEvent(StreamEventCancelled, json.RawMessage(`{"cancelled":true}`))
+	s.close()
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	return true
+}
+
+// close records the done event; callers hold s.mu
+func (s *StreamingSession) close() {
+	s.recordEvent(StreamEventDone, json.RawMessage(`{"response":"","done":true}`))
+	s.done = true
+	s.LastPoll = time.Now()
+	s.cond.Broadcast()
+}
+
+// IsDone reports whether the stream is complete
+func (s *StreamingSession) IsDone() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// GetContent returns the reply text streamed so far
+func (s *StreamingSession) GetContent() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return string(s.content)
+}
+
+// ContentAfter returns the reply text from byte offset on, the offset to read from
+// next and whether the stream is complete
+func (s *StreamingSession) ContentAfter(offset int) (string, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.contentAfter(offset)
+}
+
+func (s *StreamingSession) contentAfter(offset int) (string, int, bool) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(s.content) {
+		offset = len(s.content)
+	}
+	return string(s.content[offset:]), len(s.content), s.done
+}
+
+// WaitContent blocks until there is reply text after offset, the stream is done or
+// ctx ends, and then behaves like ContentAfter
+func (s *StreamingSession) WaitContent(ctx context.Context, offset int) (string, int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.wait(ctx, func() bool { return len(s.content) > offset })
+	content, next, done := s.contentAfter(offset)
+	return content, next, done, err
+}
+
+// WaitDone blocks until the stream is complete or ctx ends
+func (s *StreamingSession) WaitDone(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wait(ctx, func() bool { return false })
+}
+
+// EventsAfter returns the events recorded after the event with the given ID and
+// whether the stream is complete
+func (s *StreamingSession) EventsAfter(id int64) ([]StreamEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.eventsAfter(id), s.done
+}
+
+func (s *StreamingSession) eventsAfter(id int64) []StreamEvent {
+	if id < 0 {
+		id = 0
+	}
+	if id >= int64(len(s.events)) {
+		return nil
+	}
+	events := make([]StreamEvent, len(s.events)-int(id))
+	copy(events, s.events[id:])
+	return events
+}
+
+// wait blocks until ready reports true, the stream is done or ctx ends. It returns
+// ctx.Err() only when it gave up because of ctx. Callers hold s.mu.
+func (s *StreamingSession) wait(ctx context.Context, ready func() bool) error {
+	if s.cond == nil {
+		s.cond = sync.NewCond(&s.mu)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.cond.Broadcast()
+	})
+	defer stop()
+
+	for !ready() && !s.done {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.cond.Wait()
+	}
+	return nil
+}
+
+// Subscribe returns a reader that starts after the event with the given ID
+func (s *StreamingSession) Subscribe(after int64) *StreamCursor {
+	return &StreamCursor{session: s, after: after}
+}
+
+// StreamCursor reads the events of a streaming session in order from its own position
+type StreamCursor struct {
+	session *StreamingSession
+	after   int64
+}
+
+// Next blocks until events follow the cursor and returns them. Once every event of
+// a completed stream has been returned it reports io.EOF; when ctx ends first it
+// reports ctx.Err().
+func (c *StreamCursor) Next(ctx context.Context) ([]StreamEvent, error) {
+	s := c.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.wait(ctx, func() bool { return int64(len(s.events)) > c.after })
+	events := s.eventsAfter(c.after)
+	if len(events) > 0 {
+		c.after = events[len(events)-1].ID
+		return events, nil
+	}
+	if s.done {
+		return nil, io.EOF
+	}
+	return nil, err
+}
+
+// Position returns the ID of the last event the cursor returned
+func (c *StreamCursor) Position() int64 {
+	return c.after
+}
+
+// IsExpired checks if the session has expired
+func (s *StreamingSession) IsExpired() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().After(s.ExpiresAt)
+}
+
+// UpdateLastPoll updates the last poll time
+func (s *StreamingSession) UpdateLastPoll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LastPoll = time.Now()
+}
+
+// IdleSince returns when the session was last written to or polled
+func (s *StreamingSession) IdleSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.LastPoll
+}
+
+// GetStats returns session statistics
+func (s *StreamingSession) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]interface{}{
+		"id":             s.ID,
+		"created_at":     s.CreatedAt,
+		"last_poll":      s.LastPoll,
+		"expires_at":     s.ExpiresAt,
+		"done":           s.done,
+		"content_length": len(s.content),
+		"events":         len(s.events),
+		"bytes":          s.size,
+	}
+}
@@ -0,0 +1,207 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func eventTypes(events []StreamEvent) []string {
+	types := make([]string, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func TestStreamingSessionContentAfter(t *testing.T) {
+	s := NewStreamingSession("token")
+	s.AppendContent("Hello")
+	s.AppendContent("")
+	s.AppendContent(", world")
+
+	tests := []struct {
+		offset  int
+		content string
+	}{
+		{-1, "Hello, world"},
+		{0, "Hello, world"},
+		{5, ", world"},
+		{12, ""},
+		{40, ""},
+	}
+	for _, tt := range tests {
+		content, next, done := s.ContentAfter(tt.offset)
+		if content != tt.content || next != 12 || done {
+			t.Errorf("ContentAfter(%d) = %q, %d, %v; want %q, 12, false", tt.offset, content, next, done, tt.content)
+		}
+	}
+
+	s.MarkDone()
+	s.AppendContent("ignored")
+	if content, next, done := s.ContentAfter(5); content != ", world" || next != 12 || !done {
+		t.Errorf("ContentAfter(5) once done = %q, %d, %v; want \", world\", 12, true", content, next, done)
+	}
+	if err := s.RecordEvent(StreamEventToken, "late"); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("RecordEvent() once done = %v; want ErrStreamClosed", err)
+	}
+}
+
+func TestStreamingSessionEventsAfter(t *testing.T) {
+	s := NewStreamingSession("token")
+	s.AppendContent("a")
+	s.AppendContent("b")
+	s.Fail("model unavailable")
+	s.MarkDone()
+
+	tests := []struct {
+		after int64
+		types []string
+	}{
+		{-5, []string{StreamEventToken, StreamEventToken, StreamEventError, StreamEventDone}},
+		{0, []string{StreamEventToken, StreamEventToken, StreamEventError, StreamEventDone}},
+		{2, []string{StreamEventError, StreamEventDone}},
+		{4, []string{}},
+		{9, []string{}},
+	}
+	for _, tt := range tests {
+		events, done := s.EventsAfter(tt.after)
+		if got := eventTypes(events); strings.Join(got, ",") != strings.Join(tt.types, ",") || !done {
+			t.Errorf("EventsAfter(%d) = %v, %v; want %v, true", tt.after, got, done, tt.types)
+		}
+		first := max(tt.after, 0) + 1
+		for i, event := range events {
+			if event.ID != first+int64(i) {
+				t.Errorf("EventsAfter(%d)[%d].ID = %d; want %d", tt.after, i, event.ID, first+int64(i))
+			}
+		}
+	}
+}
+
+func TestStreamCursorsReadIndependently(t *testing.T) {
+	s := NewStreamingSession("token")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const readers = 3
+	results := make([][]string, readers)
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cursor := s.Subscribe(0)
+			for {
+				events, err := cursor.Next(ctx)
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					t.Errorf("reader %d: %v", i, err)
+					return
+				}
+				results[i] = append(results[i], eventTypes(events)...)
+			}
+		}(i)
+	}
+
+	for _, token := range []string{"one ", "two ", "three"} {
+		s.AppendContent(token)
+	}
+	s.MarkDone()
+	wg.Wait()
+
+	want := []string{StreamEventToken, StreamEventToken, StreamEventToken, StreamEventDone}
+	for i, got := range results {
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("reader %d read %v; want %v", i, got, want)
+		}
+	}
+
+	resumed := s.Subscribe(2)
+	events, err := resumed.Next(ctx)
+	if err != nil || len(events) != 2 || events[0].ID != 3 || resumed.Position() != 4 {
+		t.Errorf("cursor resumed after 2 read %v, %v at position %d; want events 3 and 4", eventTypes(events), err, resumed.Position())
+	}
+	if _, err := resumed.Next(ctx); err != io.EOF {
+		t.Errorf("Next() at the end = %v; want io.EOF", err)
+	}
+}
+
+func TestStreamingSessionWait(t *testing.T) {
+	s := NewStreamingSession("token")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, _, err := s.WaitContent(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitContent() on an idle stream = %v; want context.DeadlineExceeded", err)
+	}
+	if _, err := s.Subscribe(0).Next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Next() on an idle stream = %v; want context.DeadlineExceeded", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.AppendContent("hi")
+	}()
+	content, next, done, err := s.WaitContent(context.Background(), 0)
+	if content != "hi" || next != 2 || done || err != nil {
+		t.Errorf("WaitContent() = %q, %d, %v, %v; want \"hi\", 2, false, nil", content, next, done, err)
+	}
+
+	go s.MarkDone()
+	content, next, done, err = s.WaitContent(context.Background(), 2)
+	if content != "" || next != 2 || !done || err != nil {
+		t.Errorf("WaitContent() as the stream ends = %q, %d, %v, %v; want \"\", 2, true, nil", content, next, done, err)
+	}
+}
+
+func TestStreamingSessionByteCap(t *testing.T) {
+	s := NewStreamingSession("token")
+	chunk := strings.Repeat("x", 64<<10)
+
+	appended := 0
+	for i := 0; i < 2*MaxStreamBytes/len(chunk) && !s.IsDone(); i++ {
+		s.AppendContent(chunk)
+		appended++
+	}
+	if !s.IsDone() {
+		t.Fatalf("stream still open after %d bytes", appended*len(chunk))
+	}
+	if size := len(s.GetContent()); size == 0 || size > MaxStreamBytes/2 {
+		t.Errorf("stream kept %d bytes of content; want some, at most %d", size, MaxStreamBytes/2)
+	}
+	if stats := s.GetStats(); stats["bytes"].(int) > MaxStreamBytes {
+		t.Errorf("stream holds %d bytes; want at most %d", stats["bytes"], MaxStreamBytes)
+	}
+
+	events, _ := s.EventsAfter(0)
+	types := eventTypes(events)
+	if n := len(types); n < 2 || types[n-2] != StreamEventError || types[n-1] != StreamEventDone {
+		t.Errorf("stream ends with %v; want an error event then done", types[max(0, n-2):])
+	}
+	if err := s.RecordEvent(StreamEventToken, "more"); !errors.Is(err, ErrStreamClosed) {
+		t.Errorf("RecordEvent() past the cap = %v; want ErrStreamClosed", err)
+	}
+}
+
+func TestStreamingSessionCancel(t *testing.T) {
+	s := NewStreamingSession("token")
+	cancelled := false
+	s.SetCancel(func() { cancelled = true })
+
+	if !s.Cancel() || !cancelled {
+		t.Fatalf("Cancel() did not stop the generation")
+	}
+	if s.Cancel() {
+		t.Errorf("Cancel() on a done stream = true; want false")
+	}
+	events, done := s.EventsAfter(0)
+	if got := strings.Join(eventTypes(events), ","); got != StreamEventCancelled+","+StreamEventDone || !done {
+		t.Errorf("events after Cancel() = %s, %v; want cancelled then done", got, done)
+	}
+}
@@ -0,0 +1,274 @@
+// Package payees works out who a transfer is for: one of the user's own accounts, one
+// of their saved payees, or an account number or UPI ID given outright. Customers name
+// payees loosely: "Ravi" for Ravi Kumar, "my brother" for the payee nicknamed
+// brother, a mistyped "Raavi", a UPI ID or "the account ending 1234".
+package payees
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/banking/ai-agents-banking/src/dao"
+	"github.com/banking/ai-agents-banking/src/entity"
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+const (
+	// minScore is the lowest score at which a payee is taken to be meant at all
+	minScore = 0.75
+	// clearLead is how far the best match must lead the next one to be chosen alone
+	clearLead = 0.15
+)
+
+// Match is a payee a recipient may refer to
+type Match struct {
+	Payee models.Payee `json:"payee"`
+	Score float64      `json:"score"` // 1 for an exact match
+	Field string       `json:"field"` // name, nick_name, upi_id or account_no
+}
+
+// Resolution is the outcome of resolving a recipient. Exactly one of Account, Payee
+// and External is set when the recipient was resolved; Matches lists the candidates,
+// best first, when several payees match about equally well.
+type Resolution struct {
+	Query    string
+	Account  *models.Account // One of the user's own accounts
+	Payee    *models.Payee
+	External bool // An account number or UPI ID that is not a saved payee, paid as given
+	Matches  []Match
+}
+
+// Resolved reports whether the recipient names exactly one destination
+func (r Resolution) Resolved() bool {
+	return r.Account != nil || r.Payee != nil || r.External
+}
+
+// Ambiguous reports whether the recipient matched several payees equally well
+func (r Resolution) Ambiguous() bool {
+	return !r.Resolved() && len(r.Matches) > 1
+}
+
+// AccountID is where the money goes: the own account's ID, the payee's account number
+// or UPI ID, or the external account or UPI ID as given
+func (r Resolution) AccountID() string {
+	switch {
+	case r.Account != nil:
+		return r.Account.AccountID
+	case r.Payee != nil && r.Payee.AccountNo != "":
+		return r.Payee.AccountNo
+	case r.Payee != nil:
+		return r.Payee.UPIId
+	}
+	return r.Query
+}
+
+// Name is what the recipient is called on a transfer
+func (r Resolution) Name() string {
+	if r.Payee != nil {
+		return r.Payee.Name
+	}
+	return r.Query
+}
+
+// Resolver works out who a transfer is for, from the user's own accounts and saved
+// payees. Every path that moves money resolves recipients through the same Resolver.
+type Resolver struct {
+	accountDAO *dao.AccountDAO
+	payeeDAO   *dao.PayeeDAO
+}
+
+func NewResolver(accountDAO *dao.AccountDAO, payeeDAO *dao.PayeeDAO) *Resolver {
+	return &Resolver{accountDAO: accountDAO, payeeDAO: payeeDAO}
+}
+
+var (
+	// Words that come before a payee, as in "my brother" or "to the landlord"
+	fillerWordRegex = regexp.MustCompile(`(?i)^(?:(?:to|my|our|the|mr|mrs|ms|dr)\.?\s+)+`)
+	digitsRegex     = regexp.MustCompile(`^\d{3,18}$`)
+	// A full account number, as utils.IsValidAccountNumber accepts
+	accountNumberRegex = regexp.MustCompile(`^\d{9,18}$`)
+)
+
+// Resolve works out who a recipient is. Own accounts are matched by ID or number
+// first; then the user's active payees, where a UPI ID, a full account number or the
+// last digits of one ("ending 1234", or just "1234") must match exactly and names and
+// nicknames are matched fuzzily, word by word, so "ravi" finds Ravi Kumar and "Raavi
+// Kumar" finds him too. An account number or UPI ID no payee has is external.
+func (r *Resolver) Resolve(userID string, recipient string) Resolution {
+	resolution := Resolution{Query: strings.TrimSpace(recipient)}
+	if resolution.Query == "" {
+		return resolution
+	}
+
+	if accounts, err := r.accountDAO.GetUserAccounts(userID); err == nil {
+		for i, account := range accounts {
+			if account.AccountID == resolution.Query || account.AccountNumber == resolution.Query {
+				resolution.Account = &accounts[i]
+				return resolution
+			}
+		}
+	}
+
+	matches := r.matchPayees(userID, resolution.Query)
+	switch {
+	case len(matches) == 0:
+		_, upi := entity.FindUPIID(resolution.Query)
+		resolution.External = upi || accountNumberRegex.MatchString(resolution.Query)
+	case len(matches) == 1 || matches[0].Score-matches[1].Score >= clearLead:
+		resolution.Payee = &matches[0].Payee
+	default:
+		for _, match := range matches {
+			if matches[0].Score-match.Score < clearLead {
+				resolution.Matches = append(resolution.Matches, match)
+			}
+		}
+	}
+	return resolution
+}
+
+// ResolvePayee resolves to a saved payee already chosen by its ID
+func (r *Resolver) ResolvePayee(userID string, payeeID string) (Resolution, error) {
+	payee, err := r.payeeDAO.GetUserPayee(userID, payeeID)
+	if err != nil {
+		return Resolution{}, err
+	}
+	return Resolution{Query: payee.Name, Payee: payee}, nil
+}
+
+// matchPayees scores the user's active payees against a recipient, best first
+func (r *Resolver) matchPayees(userID string, recipient string) []Match {
+	payees, err := r.payeeDAO.GetUserPayees(userID)
+	if err != nil {
+		return nil
+	}
+	var matches []Match
+	for _, payee := range payees {
+		if !payee.IsActive {
+			continue
+		}
+		if match, ok := score(payee, recipient); ok {
+			matches = append(matches, match)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+// score rates how well a recipient refers to a payee
+func score(payee models.Payee, recipient string) (Match, bool) {
+	match := Match{Payee: payee}
+
+	if upi, ok := entity.FindUPIID(recipient); ok {
+		if payee.UPIId != "" && strings.EqualFold(payee.UPIId, upi.ID) {
+			match.Score, match.Field = 1, "upi_id"
+			return match, true
+		}
+		return match, false
+	}
+	digits := strings.ReplaceAll(recipient, " ", "")
+	if suffix, ok := entity.FindAccountSuffix(recipient); ok {
+		digits = suffix.Digits
+	}
+	if digitsRegex.MatchString(digits) {
+		if payee.AccountNo != "" && strings.HasSuffix(payee.AccountNo, digits) && (len(digits) <= 6 || digits == payee.AccountNo) {
+			match.Score, match.Field = 1, "account_no"
+			return match, true
+		}
+		return match, false
+	}
+
+	query := normalize(recipient)
+	for _, field := range []struct{ name, value string }{{"name", payee.Name}, {"nick_name", payee.NickName}} {
+		if field.value == "" {
+			continue
+		}
+		if s := similarity(query, normalize(field.value)); s > match.Score {
+			match.Score, match.Field = s, field.name
+		}
+	}
+	return match, match.Score >= minScore
+}
+
+// normalize lower-cases a name and drops the words said before it
+func normalize(name string) string {
+	name = strings.Trim(strings.TrimSpace(name), ".,!?")
+	name = fillerWordRegex.ReplaceAllString(name, "")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "'s"), "’s")
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// similarity scores a query against a name: 1 when they are the same, 0.9 when
+// every word of the query is a word of the name ("ravi" for "ravi kumar"), 0.8 when
+// each is the start of one ("rav kum"), and otherwise how closely the words are
+// spelt, by edit distance.
+func similarity(query, name string) float64 {
+	if query == "" {
+		return 0
+	}
+	if query == name {
+		return 1
+	}
+	queryWords, nameWords := strings.Fields(query), strings.Fields(name)
+
+	whole, prefix := true, true
+	spelling := 0.0
+	for _, q := range queryWords {
+		found, started, best := false, false, 0.0
+		for _, n := range nameWords {
+			found = found || q == n
+			started = started || len(q) >= 3 && strings.HasPrefix(n, q)
+			if s := spellingSimilarity(q, n); s > best {
+				best = s
+			}
+		}
+		whole = whole && found
+		prefix = prefix && (found || started)
+		spelling += best
+	}
+	switch {
+	case whole:
+		return 0.9
+	case prefix:
+		return 0.8
+	}
+	// Words spelt alike, or the whole name spelt alike when the words are run together
+	spelling /= float64(len(queryWords))
+	if s := spellingSimilarity(strings.ReplaceAll(query, " ", ""), strings.ReplaceAll(name, " ", "")); s > spelling {
+		spelling = s
+	}
+	return spelling * 0.95
+}
+
+// spellingSimilarity is 1 minus the edit distance between a and b over the longer length
+func spellingSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longer := len(ra)
+	if len(rb) > longer {
+		longer = len(rb)
+	}
+	if longer == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longer)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,142 @@
+package payees
+
+import (
+	"testing"
+
+	"github.com/banking/ai-agents-banking/src/dao"
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+func newTestResolver(t *testing.T) *Resolver {
+	t.Helper()
+	payeeDAO := dao.NewPayeeDAO()
+	for _, payee := range []models.Payee{
+		{ID: "P1", Name: "Ravi Kumar", AccountNo: "112233445566", IsActive: true},
+		{ID: "P2", Name: "Ravi Shah", AccountNo: "998877661234", IsActive: true},
+		{ID: "P3", Name: "Anil Mehta", NickName: "brother", UPIId: "anil@okaxis", IsActive: true},
+		{ID: "P4", Name: "Sunita Rao", AccountNo: "556677889900", IsActive: false},
+	} {
+		if err := payeeDAO.AddUserPayee("user123", payee); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewResolver(dao.NewAccountDAO(), payeeDAO)
+}
+
+func TestResolve(t *testing.T) {
+	resolver := newTestResolver(t)
+	tests := []struct {
+		name      string
+		recipient string
+		payee     string   // ID of the payee resolved to
+		account   string   // ID of the own account resolved to
+		external  bool     // Paid as given
+		matches   []string // IDs of the candidates when ambiguous
+	}{
+		{name: "exact name", recipient: "Ravi Kumar", payee: "P1"},
+		{name: "first name of two payees", recipient: "ravi", matches: []string{"P1", "P2"}},
+		{name: "misspelt", recipient: "Raavi Kumr", payee: "P1"},
+		{name: "surname", recipient: "Shah", payee: "P2"},
+		{name: "word prefixes", recipient: "rav sha", payee: "P2"},
+		{name: "nickname with filler words", recipient: "to my brother", payee: "P3"},
+		{name: "possessive", recipient: "Anil's", payee: "P3"},
+		{name: "UPI ID", recipient: "ANIL@okaxis", payee: "P3"},
+		{name: "account suffix", recipient: "the account ending 1234", payee: "P2"},
+		{name: "bare suffix", recipient: "5566", payee: "P1"},
+		{name: "full account number", recipient: "112233445566", payee: "P1"},
+		{name: "inactive payee", recipient: "Sunita Rao"},
+		{name: "unknown name", recipient: "Deepak"},
+		{name: "too far from any name", recipient: "Rahul"},
+		{name: "empty", recipient: "  "},
+		{name: "unknown account number", recipient: "123456789012", external: true},
+		{name: "unknown UPI ID", recipient: "deepak@ybl", external: true},
+		{name: "short unknown digits", recipient: "4321"},
+		{name: "own account ID", recipient: "ACC_002", account: "ACC_002"},
+		{name: "own account number", recipient: "1234567890", account: "ACC_001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolution := resolver.Resolve("user123", tt.recipient)
+
+			var payee, account string
+			if resolution.Payee != nil {
+				payee = resolution.Payee.ID
+			}
+			if resolution.Account != nil {
+				account = resolution.Account.AccountID
+			}
+			var matches []string
+			for _, match := range resolution.Matches {
+				matches = append(matches, match.Payee.ID)
+			}
+
+			if payee != tt.payee || account != tt.account || resolution.External != tt.external || !equal(matches, tt.matches) {
+				t.Errorf("Resolve(%q) = payee %q, account %q, external %v, matches %v; want payee %q, account %q, external %v, matches %v",
+					tt.recipient, payee, account, resolution.External, matches, tt.payee, tt.account, tt.external, tt.matches)
+			}
+			wantResolved := tt.payee != "" || tt.account != "" || tt.external
+			if resolution.Resolved() != wantResolved || resolution.Ambiguous() != (len(tt.matches) > 1) {
+				t.Errorf("Resolve(%q): Resolved() = %v, Ambiguous() = %v", tt.recipient, resolution.Resolved(), resolution.Ambiguous())
+			}
+		})
+	}
+}
+
+func TestResolutionAccountID(t *testing.T) {
+	resolver := newTestResolver(t)
+	tests := []struct {
+		recipient string
+		accountID string
+		name      string
+	}{
+		{"ACC_002", "ACC_002", "ACC_002"},
+		{"Ravi Kumar", "112233445566", "Ravi Kumar"},
+		{"my brother", "anil@okaxis", "Anil Mehta"},
+		{"123456789012", "123456789012", "123456789012"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.recipient, func(t *testing.T) {
+			resolution := resolver.Resolve("user123", tt.recipient)
+			if resolution.AccountID() != tt.accountID || resolution.Name() != tt.name {
+				t.Errorf("Resolve(%q) pays %q as %q; want %q as %q", tt.recipient, resolution.AccountID(), resolution.Name(), tt.accountID, tt.name)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		query, name string
+		score       float64 // Checked when not 0
+		meant       bool    // Whether the score reaches minScore
+	}{
+		{"ravi kumar", "ravi kumar", 1, true},
+		{"ravi", "ravi kumar", 0.9, true},
+		{"rav kum", "ravi kumar", 0.8, true},
+		{"raavi kumr", "ravi kumar", 0, true},
+		{"ravikumar", "ravi kumar", 0, true},
+		{"ra", "ravi kumar", 0, false},
+		{"rahul", "ravi kumar", 0, false},
+		{"", "ravi kumar", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			s := similarity(tt.query, tt.name)
+			if tt.score != 0 && s != tt.score || (s >= minScore) != tt.meant {
+				t.Errorf("similarity(%q, %q) = %.3f; want %.2f, meant %v", tt.query, tt.name, s, tt.score, tt.meant)
+			}
+		})
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
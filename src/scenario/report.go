@@ -0,0 +1,207 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report is the outcome of a run
+type Report struct {
+	Results  []Result      `json:"results"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Result is the outcome of one scenario
+type Result struct {
+	Scenario string        `json:"scenario"`
+	File     string        `json:"file"`
+	Passed   bool          `json:"passed"`
+	Failures []Failure     `json:"failures,omitempty"`
+	Turns    []Observed    `json:"turns"` // What each turn did, for reading a failure in context
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Failure is one difference between what a scenario expects and what happened
+type Failure struct {
+	Turn     int    `json:"turn"` // From 1; 0 for setup and the banking data afterwards
+	Say      string `json:"say,omitempty"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+func (r *Result) fail(turn int, say, field, expected, actual string) {
+	r.Failures = append(r.Failures, Failure{Turn: turn, Say: say, Field: field, Expected: expected, Actual: actual})
+}
+
+// Write prints the report as text: a line per scenario, the differences of those that
+// failed grouped by turn, and the totals
+func (r *Report) Write(w io.Writer) {
+	for _, result := range r.Results {
+		if result.Passed {
+			fmt.Fprintf(w, "PASS %s (%s)\n", result.Scenario, result.Duration.Round(time.Millisecond))
+			continue
+		}
+		fmt.Fprintf(w, "FAIL %s (%s)\n", result.Scenario, result.File)
+
+		group := -1
+		for _, failure := range result.Failures {
+			if failure.Turn != group {
+				group = failure.Turn
+				if group == 0 {
+					fmt.Fprintln(w, "  after the conversation")
+				} else {
+					fmt.Fprintf(w, "  turn %d %q\n", failure.Turn, failure.Say)
+				}
+			}
+			fmt.Fprintf(w, "    %s: expected %s, got %s\n", failure.Field, failure.Expected, failure.Actual)
+		}
+	}
+	fmt.Fprintf(w, "%d passed, %d failed (%s)\n", r.Passed, r.Failed, r.Duration.Round(time.Millisecond))
+}
+
+// check compares a turn with what it should have done
+func check(expect Expect, observed *Observed) []Failure {
+	var failures []Failure
+	differ := func(field, expected, actual string) {
+		failures = append(failures, Failure{Field: field, Expected: expected, Actual: actual})
+	}
+
+	if expect.Intent != "" && expect.Intent != observed.Intent {
+		differ("intent", quote(expect.Intent), quote(observed.Intent))
+	}
+	if expect.Agent != "" && expect.Agent != observed.Agent {
+		differ("agent", quote(expect.Agent), quote(observed.Agent))
+	}
+	if expect.ToolCalls != nil {
+		failures = append(failures, checkToolCalls(*expect.ToolCalls, observed.ToolCalls)...)
+	}
+	for _, text := range expect.Reply {
+		if !strings.Contains(strings.ToLower(observed.Reply), strings.ToLower(text)) {
+			differ("reply", "to contain "+quote(text), quote(observed.Reply))
+		}
+	}
+	if expect.Confirmation != nil && *expect.Confirmation != observed.Confirmation {
+		differ("confirmation", fmt.Sprint(*expect.Confirmation), fmt.Sprint(observed.Confirmation))
+	}
+	if expect.Missing != nil && !sameStrings(*expect.Missing, observed.Missing) {
+		differ("missing", render(*expect.Missing), render(observed.Missing))
+	}
+	if expect.Error != nil && *expect.Error != (len(observed.Errors) > 0) {
+		differ("error", fmt.Sprint(*expect.Error), render(observed.Errors))
+	}
+	return failures
+}
+
+func checkToolCalls(expected, actual []ToolCall) []Failure {
+	names := func(calls []ToolCall) string {
+		list := make([]string, len(calls))
+		for i, call := range calls {
+			list[i] = call.Name
+		}
+		return render(list)
+	}
+	if len(expected) != len(actual) {
+		return []Failure{{Field: "tool_calls", Expected: names(expected), Actual: names(actual)}}
+	}
+
+	var failures []Failure
+	for i := range expected {
+		field := fmt.Sprintf("tool_calls[%d]", i)
+		if expected[i].Name != actual[i].Name {
+			failures = append(failures, Failure{Field: field + ".name", Expected: quote(expected[i].Name), Actual: quote(actual[i].Name)})
+			continue
+		}
+		if expected[i].Params != nil {
+			failures = append(failures, diffSubset(field+".params", normalize(expected[i].Params), normalize(actual[i].Params))...)
+		}
+	}
+	return failures
+}
+
+// diffSubset reports where actual differs from expected. Objects only need the keys
+// expected names; arrays must match element by element.
+func diffSubset(path string, expected, actual interface{}) []Failure {
+	switch want := expected.(type) {
+	case map[string]interface{}:
+		got, ok := actual.(map[string]interface{})
+		if !ok {
+			return []Failure{{Field: path, Expected: render(expected), Actual: render(actual)}}
+		}
+		keys := make([]string, 0, len(want))
+		for key := range want {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var failures []Failure
+		for _, key := range keys {
+			value, present := got[key]
+			if !present {
+				failures = append(failures, Failure{Field: path + "." + key, Expected: render(want[key]), Actual: "nothing"})
+				continue
+			}
+			failures = append(failures, diffSubset(path+"."+key, want[key], value)...)
+		}
+		return failures
+	case []interface{}:
+		got, ok := actual.([]interface{})
+		if !ok || len(got) != len(want) {
+			return []Failure{{Field: path, Expected: render(expected), Actual: render(actual)}}
+		}
+		var failures []Failure
+		for i := range want {
+			failures = append(failures, diffSubset(fmt.Sprintf("%s[%d]", path, i), want[i], got[i])...)
+		}
+		return failures
+	default:
+		if render(expected) != render(actual) {
+			return []Failure{{Field: path, Expected: render(expected), Actual: render(actual)}}
+		}
+		return nil
+	}
+}
+
+// normalize turns a value into the maps, slices and scalars it decodes to from JSON,
+// so expectations read from a file compare equal to structs
+func normalize(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+func render(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func quote(text string) string {
+	return fmt.Sprintf("%q", text)
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,225 @@
+package scenario
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/banking/ai-agents-banking/src/fakeollama"
+)
+
+// Stack is a freshly wired server with its own banking data
+type Stack struct {
+	Handler http.Handler
+	State   func(userID string) interface{} // Banking data of a customer, as expectations see it
+	Close   func()
+}
+
+// StackFunc wires a new stack whose model is a fake answering from script
+type StackFunc func(script *fakeollama.Script) (*Stack, error)
+
+// Runner runs scenarios, each against a stack of its own so that none sees the
+// banking data another changed
+type Runner struct {
+	newStack StackFunc
+}
+
+func NewRunner(newStack StackFunc) *Runner {
+	return &Runner{newStack: newStack}
+}
+
+// Run runs every scenario and reports the outcome
+func (r *Runner) Run(scenarios []*Scenario) *Report {
+	started := time.Now()
+	report := &Report{}
+	for _, scenario := range scenarios {
+		result := r.run(scenario)
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Duration = time.Since(started)
+	return report
+}
+
+func (r *Runner) run(scenario *Scenario) (result Result) {
+	started := time.Now()
+	result = Result{Scenario: scenario.Name, File: scenario.File}
+	defer func() {
+		result.Passed = len(result.Failures) == 0
+		result.Duration = time.Since(started)
+	}()
+
+	script := scenario.LLM
+	if script == nil {
+		script = fakeollama.DefaultScript()
+	}
+	stack, err := r.newStack(script)
+	if err != nil {
+		result.fail(0, "", "setup", "a running stack", err.Error())
+		return result
+	}
+	defer stack.Close()
+
+	token, err := authenticate(stack.Handler, scenario.UserID)
+	if err != nil {
+		result.fail(0, "", "setup", "a session", err.Error())
+		return result
+	}
+
+	for i, turn := range scenario.Turns {
+		observed, err := say(stack.Handler, token, turn.Say)
+		if err != nil {
+			result.fail(i+1, turn.Say, "request", "a reply", err.Error())
+			return result // Later turns build on this one
+		}
+		result.Turns = append(result.Turns, *observed)
+		for _, failure := range check(turn.Expect, observed) {
+			failure.Turn, failure.Say = i+1, turn.Say
+			result.Failures = append(result.Failures, failure)
+		}
+	}
+
+	if scenario.State != nil {
+		for _, difference := range diffSubset("state", normalize(scenario.State), normalize(stack.State(scenario.UserID))) {
+			difference.Turn = 0
+			result.Failures = append(result.Failures, difference)
+		}
+	}
+	return result
+}
+
+// authenticate opens a session for the customer and returns its token
+func authenticate(handler http.Handler, userID string) (string, error) {
+	body, _ := json.Marshal(map[string]string{"user_id": userID})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/auth", bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		return "", fmt.Errorf("auth answered %d: %s", recorder.Code, strings.TrimSpace(recorder.Body.String()))
+	}
+
+	var auth struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &auth); err != nil || auth.Token == "" {
+		return "", fmt.Errorf("auth answered without a token: %s", strings.TrimSpace(recorder.Body.String()))
+	}
+	return auth.Token, nil
+}
+
+// Observed is what a turn did, read from the events streamed for it
+type Observed struct {
+	Intent       string     `json:"intent,omitempty"`
+	Agent        string     `json:"agent,omitempty"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	Reply        string     `json:"reply"`
+	Confirmation bool       `json:"confirmation,omitempty"`
+	Missing      []string   `json:"missing,omitempty"`
+	Errors       []string   `json:"errors,omitempty"`
+}
+
+// say sends a message through the chat endpoint and follows its event stream to the end
+func say(handler http.Handler, token, message string) (*Observed, error) {
+	body, _ := json.Marshal(map[string]string{"message": message})
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/chat", bytes.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Accept", "text/event-stream")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		return nil, fmt.Errorf("chat answered %d: %s", recorder.Code, strings.TrimSpace(recorder.Body.String()))
+	}
+
+	observed := &Observed{}
+	var reply strings.Builder
+	err := readEvents(recorder.Body, func(eventType string, data []byte) error {
+		switch eventType {
+		case "agent":
+			var event struct {
+				Agent  string `json:"agent"`
+				Intent string `json:"intent"`
+			}
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			observed.Agent, observed.Intent = event.Agent, event.Intent
+		case "tool_call":
+			var call ToolCall
+			if err := json.Unmarshal(data, &call); err != nil {
+				return err
+			}
+			observed.ToolCalls = append(observed.ToolCalls, call)
+		case "confirmation":
+			var event struct {
+				Status string `json:"status"`
+			}
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			if event.Status == "" {
+				observed.Confirmation = true // Asked for; settled confirmations carry a status
+			}
+		case "step":
+			var event struct {
+				Missing []string `json:"missing"`
+			}
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			observed.Missing = event.Missing
+		case "token":
+			var event struct {
+				Response string `json:"response"`
+			}
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			reply.WriteString(event.Response)
+		case "error":
+			var event struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			observed.Errors = append(observed.Errors, event.Error)
+		}
+		return nil
+	})
+	observed.Reply = reply.String()
+	return observed, err
+}
+
+// readEvents hands every event of a Server-Sent Events body to handle
+func readEvents(body *bytes.Buffer, handle func(eventType string, data []byte) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 8<<20)
+
+	var eventType string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if err := handle(eventType, []byte(strings.Join(data, "\n"))); err != nil {
+					return fmt.Errorf("%s event: %v", eventType, err)
+				}
+			}
+			eventType, data = "", nil
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,110 @@
+// Package scenario runs banking conversations described as data files against the
+// whole chat stack and reports how each turn, and the banking data afterwards,
+// differs from what the file expects.
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/banking/ai-agents-banking/src/fakeollama"
+)
+
+// defaultUserID is the customer a scenario talks as unless it names another
+const defaultUserID = "user123"
+
+// Scenario is a conversation with a customer and what should come of it
+type Scenario struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	UserID      string                 `json:"user_id,omitempty"` // Customer the conversation is held as; user123 when empty
+	LLM         *fakeollama.Script     `json:"llm,omitempty"`     // How the fake model answers; its default script when empty
+	Turns       []Turn                 `json:"turns"`
+	State       map[string]interface{} `json:"state,omitempty"` // Banking data expected afterwards, compared as a subset
+
+	File string `json:"-"`
+}
+
+// Turn is one customer message and what it should do
+type Turn struct {
+	Say    string `json:"say"`
+	Expect Expect `json:"expect"`
+}
+
+// Expect is what a turn should do. Fields left out are not checked.
+type Expect struct {
+	Intent       string      `json:"intent,omitempty"`
+	Agent        string      `json:"agent,omitempty"`
+	ToolCalls    *[]ToolCall `json:"tool_calls,omitempty"`     // Calls made, in order; [] expects none
+	Reply        []string    `json:"reply_contains,omitempty"` // Text the reply contains, ignoring case
+	Confirmation *bool       `json:"confirmation,omitempty"`   // Whether the turn asks for a confirmation
+	Missing      *[]string   `json:"missing,omitempty"`        // Parameters the open step still needs; [] for a complete step
+	Error        *bool       `json:"error,omitempty"`          // Whether the turn reports an error event
+}
+
+// ToolCall is a tool call a turn should make
+type ToolCall struct {
+	Name   string                 `json:"name"`
+	Params map[string]interface{} `json:"params,omitempty"` // Compared as a subset
+}
+
+// Load reads the scenarios in the given files and in the *.json files of the given
+// directories, in name order
+func Load(paths ...string) ([]*Scenario, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+
+	scenarios := make([]*Scenario, 0, len(files))
+	for _, file := range files {
+		scenario, err := loadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+func loadFile(file string) (*Scenario, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var scenario Scenario
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields() // A misspelt expectation would otherwise never be checked
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if len(scenario.Turns) == 0 {
+		return nil, fmt.Errorf("%s: no turns", file)
+	}
+	if scenario.Name == "" {
+		scenario.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	if scenario.UserID == "" {
+		scenario.UserID = defaultUserID
+	}
+	scenario.File = file
+	return &scenario, nil
+}
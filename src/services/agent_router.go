@@ -0,0 +1,151 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/banking/ai-agents-banking/src/agents"
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+// Routing weights. An owned intent outweighs any keyword evidence, keyword weights
+// are capped so a long message cannot drown out the intent, and stickiness only
+// breaks near-ties in favour of the agent already in the conversation.
+const (
+	intentMatchWeight = 2.0
+	keywordScoreCap   = 1.5
+	stickinessWeight  = 0.5
+)
+
+// routerAgentName names the clarifying responses produced by the router itself
+const routerAgentName = "AgentRouter"
+
+// RoutingDecision is the outcome of scoring every agent for a message
+type RoutingDecision struct {
+	Agent   agents.BankingAgent `json:"-"`
+	Ranking []models.AgentScore `json:"ranking"`
+	Clarify bool                `json:"clarify"`
+}
+
+// Route scores every registered agent for the message and picks the best one.
+// Agents are ranked by score, then by name, so the same input always routes the
+// same way. With no agent scoring above zero the fallback agent is chosen.
+func (s *AgentService) Route(agentCtx *models.AgentContext) RoutingDecision {
+	sticky := stickyAgent(agentCtx)
+
+	ranking := make([]models.AgentScore, 0, len(s.agents))
+	for _, agent := range s.agents {
+		ranking = append(ranking, scoreAgent(agent, agentCtx, sticky, s.ownsIntent(agent, agentCtx.Intent)))
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].Score != ranking[j].Score {
+			return ranking[i].Score > ranking[j].Score
+		}
+		return ranking[i].Agent < ranking[j].Agent
+	})
+
+	decision := RoutingDecision{Agent: s.fallback, Ranking: ranking}
+	if len(ranking) == 0 || ranking[0].Score <= 0 {
+		log.Printf("[Router] No agent matched %q, using fallback", agentCtx.Message)
+		return decision
+	}
+
+	decision.Agent = s.agents[ranking[0].Agent]
+	if len(ranking) > 1 && ranking[1].Score > 0 && ranking[0].Score-ranking[1].Score < s.margin {
+		decision.Clarify = true
+	}
+	log.Printf("[Router] %s scored %.2f for %q (clarify: %v)", ranking[0].Agent, ranking[0].Score, agentCtx.Message, decision.Clarify)
+	return decision
+}
+
+// ownsIntent reports whether an intent is routed to the agent: by the intent catalog
+// for the intents it defines, else by the agent's own routing profile
+func (s *AgentService) ownsIntent(agent agents.BankingAgent, intent string) bool {
+	if s.intents != nil {
+		if owner, known := s.intents.AgentFor(intent); known {
+			return owner == agent.GetName()
+		}
+	}
+	return agent.GetRoutingProfile().OwnsIntent(intent)
+}
+
+// scoreAgent adds up intent ownership, keywords and stickiness. The agent's own
+// confidence scales only its keyword evidence: an owned intent is certain, and a
+// less confident owner must not fall back within the margin of keyword matches.
+func scoreAgent(agent agents.BankingAgent, agentCtx *models.AgentContext, sticky string, ownsIntent bool) models.AgentScore {
+	profile := agent.GetRoutingProfile()
+	keywordScore, keywords := profile.MatchKeywords(agentCtx.Message)
+
+	score := models.AgentScore{
+		Agent:        agent.GetName(),
+		IntentMatch:  ownsIntent,
+		KeywordScore: keywordScore,
+		Keywords:     keywords,
+		Confidence:   agent.GetConfidence(),
+	}
+
+	var raw float64
+	if score.IntentMatch {
+		raw += intentMatchWeight
+	}
+	raw += math.Min(keywordScore, keywordScoreCap) * score.Confidence
+	if raw > 0 && score.Agent == sticky {
+		score.Sticky = true
+		raw += stickinessWeight
+	}
+	score.Score = math.Round(raw*1000) / 1000
+	return score
+}
+
+// stickyAgent names the agent that owns the current step or, failing that, gave the last reply
+func stickyAgent(agentCtx *models.AgentContext) string {
+	if agentCtx.CurrentStep != nil && agentCtx.CurrentStep.AgentName != "" {
+		return agentCtx.CurrentStep.AgentName
+	}
+	if agentCtx.Conversation == nil {
+		return ""
+	}
+	messages := agentCtx.Conversation.Messages
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" {
+			return messages[i].AgentName
+		}
+	}
+	return ""
+}
+
+// clarify asks the user to choose between the agents that scored too close to call
+func (s *AgentService) clarify(decision RoutingDecision) *models.AgentResponse {
+	var options []string
+	for _, score := range decision.Ranking {
+		if score.Score <= 0 || decision.Ranking[0].Score-score.Score >= s.margin {
+			break
+		}
+		options = append(options, s.topicOf(score.Agent))
+	}
+
+	question := fmt.Sprintf("Just to be sure, would you like to %s?", options[0])
+	if len(options) > 1 {
+		question = fmt.Sprintf("Just to be sure, would you like to %s or %s?",
+			strings.Join(options[:len(options)-1], ", "), options[len(options)-1])
+	}
+	return &models.AgentResponse{
+		Message:   question,
+		AgentName: routerAgentName,
+		Clarify:   true,
+	}
+}
+
+func (s *AgentService) topicOf(agentName string) string {
+	agent, exists := s.agents[agentName]
+	if !exists {
+		return agentName
+	}
+	if topic := agent.GetRoutingProfile().Topic; topic != "" {
+		return topic
+	}
+	return strings.ToLower(agent.GetDescription())
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/banking/ai-agents-banking/src/agents"
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+// routedAgent is an agent that is only ever scored, never run
+type routedAgent struct {
+	agents.BankingAgent
+	name       string
+	confidence float64
+	profile    agents.RoutingProfile
+}
+
+func (a *routedAgent) GetName() string                          { return a.name }
+func (a *routedAgent) GetDescription() string                   { return a.name }
+func (a *routedAgent) GetConfidence() float64                   { return a.confidence }
+func (a *routedAgent) GetRoutingProfile() agents.RoutingProfile { return a.profile }
+
+func newTestRouter(margin float64) *AgentService {
+	service := &AgentService{
+		agents:   make(map[string]agents.BankingAgent),
+		fallback: &routedAgent{name: "General"},
+		margin:   margin,
+	}
+	for _, agent := range []*routedAgent{
+		{name: "Transfer", confidence: 1, profile: agents.RoutingProfile{
+			Intents:  []string{"transfer_money"},
+			Keywords: map[string]float64{"send": 0.6, "transfer": 1, "pay": 0.6, "money": 0.3},
+			Topic:    "transfer money",
+		}},
+		{name: "Balance", confidence: 1, profile: agents.RoutingProfile{
+			Intents:  []string{"check_balance"},
+			Keywords: map[string]float64{"balance": 1, "money": 0.3, "how much": 0.6},
+			Topic:    "check your balance",
+		}},
+		{name: "Loan", confidence: 0.8, profile: agents.RoutingProfile{
+			Intents:  []string{"apply_loan"},
+			Keywords: map[string]float64{"loan": 1.5, "emi": 1, "borrow": 1, "pay": 0.6},
+			Topic:    "apply for a loan",
+		}},
+		{name: "Alpha", confidence: 1, profile: agents.RoutingProfile{
+			Keywords: map[string]float64{"statement": 1},
+			Topic:    "get a statement",
+		}},
+		{name: "Beta", confidence: 1, profile: agents.RoutingProfile{
+			Keywords: map[string]float64{"statement": 1},
+			Topic:    "download a statement",
+		}},
+	} {
+		service.RegisterAgent(agent)
+	}
+	return service
+}
+
+func quietLog(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+}
+
+func TestRoute(t *testing.T) {
+	quietLog(t)
+
+	tests := []struct {
+		name     string
+		ctx      *models.AgentContext
+		noMargin bool
+		agent    string
+		score    float64
+		clarify  bool
+	}{
+		{
+			name:  "owned intent outweighs keywords",
+			ctx:   &models.AgentContext{Intent: "check_balance", Message: "send pay"},
+			agent: "Balance", score: 2,
+		},
+		{
+			name:  "owned intent outweighs capped keywords",
+			ctx:   &models.AgentContext{Intent: "check_balance", Message: "transfer send pay"},
+			agent: "Balance", score: 2,
+		},
+		{
+			name:  "keywords alone",
+			ctx:   &models.AgentContext{Message: "please transfer 500"},
+			agent: "Transfer", score: 1,
+		},
+		{
+			name:  "keyword score is capped",
+			ctx:   &models.AgentContext{Intent: "transfer_money", Message: "send transfer pay money"},
+			agent: "Transfer", score: intentMatchWeight + keywordScoreCap,
+		},
+		{
+			name:  "confidence scales the keyword score",
+			ctx:   &models.AgentContext{Intent: "apply_loan", Message: "a loan"},
+			agent: "Loan", score: 3.2,
+		},
+		{
+			name:  "a less confident owner outweighs capped keywords",
+			ctx:   &models.AgentContext{Intent: "apply_loan", Message: "transfer send money"},
+			agent: "Loan", score: 2,
+		},
+		{
+			name:  "no match uses the fallback",
+			ctx:   &models.AgentContext{Message: "hello there"},
+			agent: "General",
+		},
+		{
+			name:  "near tie asks which was meant",
+			ctx:   &models.AgentContext{Message: "how much money can I send"},
+			agent: "Balance", score: 0.9, clarify: true,
+		},
+		{
+			name:  "exact tie is broken by name",
+			ctx:   &models.AgentContext{Message: "my statement"},
+			agent: "Alpha", score: 1, clarify: true,
+		},
+		{
+			name:     "a zero margin never clarifies",
+			ctx:      &models.AgentContext{Message: "my statement"},
+			noMargin: true,
+			agent:    "Alpha", score: 1,
+		},
+		{
+			name: "the current step's agent wins a tie",
+			ctx: &models.AgentContext{Message: "my statement",
+				CurrentStep: &models.ConversationStep{AgentName: "Beta"}},
+			agent: "Beta", score: 1 + stickinessWeight,
+		},
+		{
+			name: "the last replying agent wins a tie",
+			ctx: &models.AgentContext{Message: "my statement", Conversation: &models.Conversation{Messages: []models.Message{
+				{Role: "assistant", AgentName: "Alpha"},
+				{Role: "assistant", AgentName: "Beta"},
+				{Role: "user"},
+			}}},
+			agent: "Beta", score: 1 + stickinessWeight,
+		},
+		{
+			name: "stickiness does not outweigh an owned intent",
+			ctx: &models.AgentContext{Intent: "check_balance", Message: "balance",
+				CurrentStep: &models.ConversationStep{AgentName: "Transfer"}},
+			agent: "Balance", score: 3,
+		},
+		{
+			name: "stickiness alone does not route",
+			ctx: &models.AgentContext{Message: "hello there",
+				CurrentStep: &models.ConversationStep{AgentName: "Transfer"}},
+			agent: "General",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			margin := 0.25
+			if tt.noMargin {
+				margin = 0
+			}
+			decision := newTestRouter(margin).Route(tt.ctx)
+
+			if decision.Agent.GetName() != tt.agent || decision.Clarify != tt.clarify {
+				t.Errorf("Route() = %s (clarify %v); want %s (clarify %v); ranking %+v",
+					decision.Agent.GetName(), decision.Clarify, tt.agent, tt.clarify, decision.Ranking)
+			}
+			if tt.score != 0 && decision.Ranking[0].Score != tt.score {
+				t.Errorf("Route() best score = %v; want %v", decision.Ranking[0].Score, tt.score)
+			}
+		})
+	}
+}
+
+func TestRouteRankingIsStable(t *testing.T) {
+	quietLog(t)
+	router := newTestRouter(0.25)
+	ctx := &models.AgentContext{Message: "my statement for the money I pay"}
+	first := router.Route(ctx).Ranking
+	for i := 0; i < 20; i++ {
+		ranking := router.Route(ctx).Ranking
+		for j := range ranking {
+			if ranking[j].Agent != first[j].Agent || ranking[j].Score != first[j].Score {
+				t.Fatalf("ranking %d differs: %+v; first %+v", i, ranking, first)
+			}
+		}
+	}
+}
+
+func TestClarify(t *testing.T) {
+	quietLog(t)
+	router := newTestRouter(0.25)
+	tests := []struct {
+		message  string
+		question string
+	}{
+		{"my statement", "Just to be sure, would you like to get a statement or download a statement?"},
+		{"how much money can I send", "Just to be sure, would you like to check your balance or transfer money?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			decision := router.Route(&models.AgentContext{Message: tt.message})
+			response := router.clarify(decision)
+			if response.Message != tt.question || !response.Clarify || response.AgentName != routerAgentName {
+				t.Errorf("clarify() = %q (clarify %v, agent %s); want %q", response.Message, response.Clarify, response.AgentName, tt.question)
+			}
+		})
+	}
+}
@@ -1,37 +1,50 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/banking/ai-agents-banking/src/agents"
 	"github.com/banking/ai-agents-banking/src/dao"
 	"github.com/banking/ai-agents-banking/src/models"
+	"github.com/banking/ai-agents-banking/src/payees"
+	"github.com/banking/ai-agents-banking/src/utils"
 )
 
 type AgentService struct {
 	agents      map[string]agents.BankingAgent
 	fallback    agents.BankingAgent
+	margin      float64
+	intents     *IntentCatalog
 	accountDAO  *dao.AccountDAO
 	payeeDAO    *dao.PayeeDAO
+	recipients  *payees.Resolver
 	transferDAO *dao.TransferDAO
 	loanDAO     *dao.LoanDAO
 }
 
-func NewAgentService(accountDAO *dao.AccountDAO, payeeDAO *dao.PayeeDAO, transferDAO *dao.TransferDAO, loanDAO *dao.LoanDAO) *AgentService {
+// NewAgentService registers the banking agents. Messages whose two best-scoring
+// agents are closer than margin get a clarifying question instead of a guess.
+func NewAgentService(accountDAO *dao.AccountDAO, payeeDAO *dao.PayeeDAO, transferDAO *dao.TransferDAO, loanDAO *dao.LoanDAO, margin float64) *AgentService {
 	service := &AgentService{
 		agents:      make(map[string]agents.BankingAgent),
+		margin:      margin,
 		accountDAO:  accountDAO,
 		payeeDAO:    payeeDAO,
+		recipients:  payees.NewResolver(accountDAO, payeeDAO),
 		transferDAO: transferDAO,
 		loanDAO:     loanDAO,
 	}
 
 	// Register all agents
-	service.RegisterAgent(agents.NewFundTransferAgent(accountDAO, payeeDAO, transferDAO))
+	service.RegisterAgent(agents.NewFundTransferAgent(accountDAO, payeeDAO, transferDAO, service.recipients))
 	service.RegisterAgent(agents.NewAccountBalanceAgent(accountDAO))
 	service.RegisterAgent(agents.NewAddPayeeAgent(payeeDAO))
 	service.RegisterAgent(agents.NewLoanAgent(loanDAO))
+	service.RegisterAgent(agents.NewDepositAgent())
 
 	// Set fallback agent
 	service.fallback = agents.NewGeneralBankingAgent()
@@ -43,21 +56,46 @@ func (s *AgentService) RegisterAgent(agent agents.BankingAgent) {
 	s.agents[agent.GetName()] = agent
 }
 
+// UseIntentCatalog routes the intents the catalog defines to the agents it maps them to
+func (s *AgentService) UseIntentCatalog(intents *IntentCatalog) {
+	s.intents = intents
+}
+
+// GetAgent returns the agent that scores best for the intent and message, or the fallback agent
 func (s *AgentService) GetAgent(intent string, message string) agents.BankingAgent {
-	// Find the best matching agent
-	for _, agent := range s.agents {
-		if agent.CanHandle(intent, message) {
-			return agent
-		}
+	return s.Route(&models.AgentContext{Intent: intent, Message: message}).Agent
+}
+
+// ProcessWithAgent routes the message and lets the chosen agent handle it. When the
+// two best agents score within the margin, the user is asked which one they meant.
+func (s *AgentService) ProcessWithAgent(ctx context.Context, agentCtx *models.AgentContext) *models.AgentResponse {
+	decision := s.Route(agentCtx)
+	var response *models.AgentResponse
+	if decision.Clarify {
+		response = s.clarify(decision)
+	} else {
+		response = decision.Agent.Process(ctx, agentCtx)
 	}
+	response.Ranking = decision.Ranking
+	return response
+}
 
-	// Return fallback if no specific agent can handle
+// GetAgentByName returns a registered agent by name, or the fallback agent
+func (s *AgentService) GetAgentByName(name string) agents.BankingAgent {
+	if agent, exists := s.agents[name]; exists {
+		return agent
+	}
 	return s.fallback
 }
 
-func (s *AgentService) ProcessWithAgent(ctx *models.AgentContext) *models.AgentResponse {
-	agent := s.GetAgent(ctx.Intent, ctx.Message)
-	return agent.Process(ctx)
+// ProcessWithNamedAgent continues a conversation with the agent that owns it
+func (s *AgentService) ProcessWithNamedAgent(ctx context.Context, agentName string, agentCtx *models.AgentContext) *models.AgentResponse {
+	return s.GetAgentByName(agentName).Process(ctx, agentCtx)
+}
+
+// IsFallback reports whether the named agent is the general fallback agent
+func (s *AgentService) IsFallback(agentName string) bool {
+	return s.fallback != nil && s.fallback.GetName() == agentName
 }
 
 func (s *AgentService) GetAllAgents() map[string]agents.BankingAgent {
@@ -73,96 +111,236 @@ func (s *AgentService) GetAgentsCount() int {
 }
 
 // Banking operations
-func (s *AgentService) ExecuteTransfer(amount float64, recipient string, method string) (interface{}, error) {
-	// Create transfer request
-	transfer := models.TransferRequest{
-		TransferID: fmt.Sprintf("TXN%d", time.Now().UnixNano()),
-		Amount:     amount,
-		Method:     method,
-		Status:     "SUCCESS",
-		Timestamp:  time.Now(),
-		Reference:  fmt.Sprintf("REF%d", time.Now().UnixNano()),
-		Fees:       0, // Calculate based on method
-	}
-
-	// Store transfer
-	transferRecord := models.Transfer{
-		TransferID:    transfer.TransferID,
-		FromAccountID: "", // Set appropriately if you have account info
-		ToAccountID:   "", // Set appropriately if you have recipient info
-		Amount:        transfer.Amount,
-		Method:        transfer.Method,
-		Status:        transfer.Status,
-		Timestamp:     transfer.Timestamp,
-		Reference:     transfer.Reference,
-		Fees:          transfer.Fees,
-		Description:   transfer.Description,
-	}
-	err := s.transferDAO.AddTransfer("user_id", transferRecord)
+//
+// Every operation runs on behalf of a principal and only touches that user's data.
+
+// sourceAccount returns the account the principal operates on: the selected one, or the primary account
+func (s *AgentService) sourceAccount(principal models.Principal) (*models.Account, error) {
+	if principal.UserID == "" {
+		return nil, fmt.Errorf("no user to act on behalf of")
+	}
+	if principal.AccountID != "" {
+		return s.accountDAO.GetAccountByID(principal.UserID, principal.AccountID)
+	}
+	return s.accountDAO.GetUserAccount(principal.UserID)
+}
+
+// resolveRecipient works out where a transfer goes through the same resolver the
+// fund transfer agent asks the user about, by payeeID when the payee was already
+// chosen. A recipient that matches no payee, or several, is an error.
+func (s *AgentService) resolveRecipient(userID string, recipient string, payeeID string) (payees.Resolution, error) {
+	if payeeID != "" {
+		return s.recipients.ResolvePayee(userID, payeeID)
+	}
+	resolution := s.recipients.Resolve(userID, recipient)
+	switch {
+	case resolution.Ambiguous():
+		names := make([]string, len(resolution.Matches))
+		for i, match := range resolution.Matches {
+			names[i] = match.Payee.Name
+		}
+		return resolution, fmt.Errorf("%q matches several payees: %s", recipient, strings.Join(names, ", "))
+	case !resolution.Resolved():
+		return resolution, fmt.Errorf("no payee matches %q; add them as a payee first", recipient)
+	}
+	return resolution, nil
+}
+
+// SelectAccount makes one of the session user's accounts the default for later operations
+func (s *AgentService) SelectAccount(session *models.UserSession, accountID string) error {
+	if _, err := s.accountDAO.GetAccountByID(session.GetAccountID(), accountID); err != nil {
+		return err
+	}
+	session.SelectAccount(accountID)
+	return nil
+}
+
+// ExecuteTransfer moves money from the principal's account to a recipient. payeeID,
+// when set, is the saved payee the recipient was already resolved to.
+func (s *AgentService) ExecuteTransfer(ctx context.Context, principal models.Principal, amount float64, recipient string, payeeID string, method string) (interface{}, error) {
+	source, err := s.sourceAccount(principal)
 	if err != nil {
 		return nil, err
 	}
 
-	return transfer, nil
-}
+	to, err := s.resolveRecipient(principal.UserID, recipient, payeeID)
+	if err != nil {
+		return nil, err
+	}
+	toAccountID, own := to.AccountID(), to.Account != nil
+	if toAccountID == source.AccountID {
+		return nil, fmt.Errorf("cannot transfer to the account the money comes from")
+	}
 
-func (s *AgentService) GetBalance(accountID string) (float64, error) {
-	account, err := s.accountDAO.GetUserAccount(accountID)
+	// Never move money for a request that has already been abandoned or timed out
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fees := utils.CalculateTransferFees(method, amount)
+	debited, err := s.accountDAO.DebitAccount(principal.UserID, source.AccountID, amount+fees)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	credited := ""
+	if own {
+		if _, err := s.accountDAO.CreditAccount(principal.UserID, toAccountID, amount); err != nil {
+			s.reverseTransfer(principal.UserID, source.AccountID, amount+fees, "", 0)
+			return nil, err
+		}
+		credited = toAccountID
+	}
+
+	transfer := models.Transfer{
+		TransferID:    fmt.Sprintf("TXN%d", time.Now().UnixNano()),
+		FromAccountID: source.AccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+		Method:        method,
+		Status:        "SUCCESS",
+		Timestamp:     time.Now(),
+		Reference:     fmt.Sprintf("REF%d", time.Now().UnixNano()),
+		Fees:          fees,
+		Description:   fmt.Sprintf("Transfer to %s", to.Name()),
+	}
+	if err := s.transferDAO.AddTransfer(principal.UserID, transfer); err != nil {
+		// A transfer without a record never happened, so the money goes back
+		s.reverseTransfer(principal.UserID, source.AccountID, amount+fees, credited, amount)
+		return nil, err
+	}
+
+	log.Printf("[AgentService] Transfer %s: ₹%.2f from %s to %s for user %s", transfer.TransferID, amount, source.AccountID, toAccountID, principal.UserID)
+	return map[string]interface{}{
+		"transfer":          transfer,
+		"available_balance": debited.Balance,
+	}, nil
+}
+
+// reverseTransfer puts back the money a transfer that could not be completed moved:
+// the debit from the source account and, for an own account, the credit to it
+func (s *AgentService) reverseTransfer(userID, sourceID string, debited float64, creditedID string, credited float64) {
+	if creditedID != "" {
+		if _, err := s.accountDAO.DebitAccount(userID, creditedID, credited); err != nil {
+			log.Printf("[AgentService] Cannot take back ₹%.2f credited to %s for user %s: %v", credited, creditedID, userID, err)
+		}
+	}
+	if _, err := s.accountDAO.CreditAccount(userID, sourceID, debited); err != nil {
+		log.Printf("[AgentService] Cannot refund ₹%.2f to %s for user %s: %v", debited, sourceID, userID, err)
+	}
+}
+
+// GetAccounts returns the accounts of the principal's user
+func (s *AgentService) GetAccounts(principal models.Principal) ([]models.Account, error) {
+	return s.accountDAO.GetUserAccounts(principal.UserID)
+}
+
+// GetBalance returns the balance of one of the principal's accounts; an empty
+// accountID means the selected or primary account
+func (s *AgentService) GetBalance(principal models.Principal, accountID string) (*models.Account, error) {
+	if accountID == "" {
+		return s.sourceAccount(principal)
 	}
-	return account.Balance, nil
+	return s.accountDAO.GetAccountByID(principal.UserID, accountID)
 }
 
-func (s *AgentService) AddPayee(name string, accountNumber string) (*models.Payee, error) {
+func (s *AgentService) AddPayee(ctx context.Context, principal models.Principal, name string, accountNumber string, ifscCode string) (*models.Payee, error) {
+	if principal.UserID == "" {
+		return nil, fmt.Errorf("no user to act on behalf of")
+	}
+
+	existing, err := s.payeeDAO.GetPayeeByAccount(principal.UserID, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("payee with account number %s already exists as '%s'", accountNumber, existing.Name)
+	}
+
 	payee := &models.Payee{
 		ID:         fmt.Sprintf("PAYEE_%d", time.Now().UnixNano()),
 		Name:       name,
 		AccountNo:  accountNumber,
+		IFSCCode:   ifscCode,
 		PayeeType:  "External",
 		AddedDate:  time.Now(),
 		IsActive:   true,
 		IsVerified: false,
 	}
+	if ifscCode != "" {
+		payee.BankName = utils.GetBankNameFromIFSC(ifscCode)
+	}
 
-	err := s.payeeDAO.AddUserPayee("user_id", *payee)
-	if err != nil {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.payeeDAO.AddUserPayee(principal.UserID, *payee); err != nil {
 		return nil, err
 	}
 
 	return payee, nil
 }
 
-func (s *AgentService) CreateFixedDeposit(amount float64, tenure int, tenureUnit string) (interface{}, error) {
+// CreateFixedDeposit funds the deposit from the principal's selected or primary account
+func (s *AgentService) CreateFixedDeposit(ctx context.Context, principal models.Principal, amount float64, tenure int, tenureUnit string) (interface{}, error) {
+	source, err := s.sourceAccount(principal)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	debited, err := s.accountDAO.DebitAccount(principal.UserID, source.AccountID, amount)
+	if err != nil {
+		return nil, err
+	}
+
 	// Mock FD creation
 	fd := map[string]interface{}{
-		"id":            fmt.Sprintf("FD_%d", time.Now().UnixNano()),
-		"amount":        amount,
-		"tenure":        tenure,
-		"tenure_unit":   tenureUnit,
-		"created_at":    time.Now(),
-		"maturity_at":   time.Now().AddDate(0, tenure, 0),
-		"interest_rate": 6.5, // Mock rate
+		"id":                fmt.Sprintf("FD_%d", time.Now().UnixNano()),
+		"amount":            amount,
+		"tenure":            tenure,
+		"tenure_unit":       tenureUnit,
+		"created_at":        time.Now(),
+		"maturity_at":       maturityDate(tenure, tenureUnit),
+		"interest_rate":     6.5, // Mock rate
+		"debit_account_id":  source.AccountID,
+		"available_balance": debited.Balance,
 	}
 
 	return fd, nil
 }
 
-func (s *AgentService) CreateRecurringDeposit(amount float64, tenure int, tenureUnit string) (interface{}, error) {
+// CreateRecurringDeposit links the deposit to the account its instalments are debited from
+func (s *AgentService) CreateRecurringDeposit(ctx context.Context, principal models.Principal, amount float64, tenure int, tenureUnit string) (interface{}, error) {
+	source, err := s.sourceAccount(principal)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Mock RD creation
 	rd := map[string]interface{}{
-		"id":            fmt.Sprintf("RD_%d", time.Now().UnixNano()),
-		"amount":        amount,
-		"tenure":        tenure,
-		"tenure_unit":   tenureUnit,
-		"created_at":    time.Now(),
-		"maturity_at":   time.Now().AddDate(0, tenure, 0),
-		"interest_rate": 7.0, // Mock rate
+		"id":               fmt.Sprintf("RD_%d", time.Now().UnixNano()),
+		"amount":           amount,
+		"tenure":           tenure,
+		"tenure_unit":      tenureUnit,
+		"created_at":       time.Now(),
+		"maturity_at":      maturityDate(tenure, tenureUnit),
+		"interest_rate":    7.0, // Mock rate
+		"debit_account_id": source.AccountID,
 	}
 
 	return rd, nil
 }
 
+func maturityDate(tenure int, tenureUnit string) time.Time {
+	if strings.HasPrefix(tenureUnit, "year") {
+		return time.Now().AddDate(tenure, 0, 0)
+	}
+	return time.Now().AddDate(0, tenure, 0)
+}
+
 func (s *AgentService) GetInterestRates(productType string) (interface{}, error) {
 	// Mock interest rates
 	rates := map[string]interface{}{
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/banking/ai-agents-banking/src/dao"
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+func TestBankingOperationsStopOnceCancelled(t *testing.T) {
+	quietLog(t)
+	accountDAO, payeeDAO, transferDAO := dao.NewAccountDAO(), dao.NewPayeeDAO(), dao.NewTransferDAO()
+	service := NewAgentService(accountDAO, payeeDAO, transferDAO, dao.NewLoanDAO(), 0.25)
+	principal := models.Principal{UserID: "user123", AccountID: "ACC_001"}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	balance := func(accountID string) float64 {
+		account, err := accountDAO.GetAccountByID("user123", accountID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return account.Balance
+	}
+	from, to := balance("ACC_001"), balance("ACC_002")
+
+	operations := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{"transfer", func(ctx context.Context) error {
+			_, err := service.ExecuteTransfer(ctx, principal, 1000, "ACC_002", "", "IMPS")
+			return err
+		}},
+		{"fixed deposit", func(ctx context.Context) error {
+			_, err := service.CreateFixedDeposit(ctx, principal, 1000, 12, "months")
+			return err
+		}},
+		{"recurring deposit", func(ctx context.Context) error {
+			_, err := service.CreateRecurringDeposit(ctx, principal, 1000, 12, "months")
+			return err
+		}},
+		{"payee", func(ctx context.Context) error {
+			_, err := service.AddPayee(ctx, principal, "Ravi Kumar", "112233445566", "HDFC0001234")
+			return err
+		}},
+	}
+	for _, op := range operations {
+		if err := op.run(cancelled); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s with a cancelled context = %v; want context.Canceled", op.name, err)
+		}
+	}
+
+	if balance("ACC_001") != from || balance("ACC_002") != to {
+		t.Errorf("balances moved to %.2f and %.2f; want %.2f and %.2f", balance("ACC_001"), balance("ACC_002"), from, to)
+	}
+	if transfers, _ := transferDAO.GetUserTransfers("user123"); len(transfers) != 0 {
+		t.Errorf("recorded %d transfers; want none", len(transfers))
+	}
+	if payees, _ := payeeDAO.GetUserPayees("user123"); len(payees) != 0 {
+		t.Errorf("saved %d payees; want none", len(payees))
+	}
+
+	if _, err := service.ExecuteTransfer(context.Background(), principal, 1000, "ACC_002", "", "IMPS"); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if balance("ACC_002") != to+1000 {
+		t.Errorf("ACC_002 holds %.2f after the transfer; want %.2f", balance("ACC_002"), to+1000)
+	}
+}
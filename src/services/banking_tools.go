@@ -1,10 +1,24 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"time"
+
+	"github.com/banking/ai-agents-banking/src/models"
 )
 
+var transferMethods = []string{"UPI", "IMPS", "NEFT", "RTGS"}
+
+// depositSchema is shared by the fixed and recurring deposit tools
+func depositSchema(amountDescription string) ToolSchema {
+	return NewToolSchema(map[string]ParameterSchema{
+		"amount":      {Type: TypeNumber, Description: amountDescription, ExclusiveMinimum: floatPtr(0)},
+		"tenure":      {Type: TypeInteger, Description: "Deposit tenure", Minimum: floatPtr(1)},
+		"tenure_unit": {Type: TypeString, Description: "Unit of the tenure", Enum: []string{"months", "years"}, Default: "months"},
+	}, "amount", "tenure")
+}
+
 // Base banking tool
 type BaseBankingTool struct {
 	AgentService *AgentService
@@ -23,7 +37,20 @@ func (t *FundTransferTool) Description() string {
 	return "Transfer funds between accounts using UPI/IMPS/NEFT"
 }
 
-func (t *FundTransferTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *FundTransferTool) Parameters() ToolSchema {
+	return NewToolSchema(map[string]ParameterSchema{
+		"amount":    {Type: TypeNumber, Description: "Amount to transfer in rupees", ExclusiveMinimum: floatPtr(0)},
+		"recipient": {Type: TypeString, Description: "Payee name or account number"},
+		"method":    {Type: TypeString, Description: "Transfer method", Enum: transferMethods, Default: "UPI"},
+		"payee_id":  {Type: TypeString, Description: "Saved payee the recipient was resolved to, if known"},
+	}, "amount", "recipient")
+}
+
+func (t *FundTransferTool) CachePolicy() CachePolicy {
+	return CachePolicy{Invalidates: []string{"check_balance", "transfer_history"}}
+}
+
+func (t *FundTransferTool) Execute(ctx context.Context, principal models.Principal, params map[string]interface{}) (interface{}, error) {
 	amount, ok := params["amount"].(float64)
 	if !ok {
 		return nil, fmt.Errorf("invalid amount")
@@ -39,8 +66,10 @@ func (t *FundTransferTool) Execute(params map[string]interface{}) (interface{},
 		method = "UPI" // Default to UPI
 	}
 
+	payeeID, _ := params["payee_id"].(string)
+
 	// Execute transfer through agent service
-	result, err := t.AgentService.ExecuteTransfer(amount, recipient, method)
+	result, err := t.AgentService.ExecuteTransfer(ctx, principal, amount, recipient, payeeID, method)
 	if err != nil {
 		return nil, err
 	}
@@ -61,21 +90,31 @@ func (t *BalanceCheckTool) Description() string {
 	return "Check account balance"
 }
 
-func (t *BalanceCheckTool) Execute(params map[string]interface{}) (interface{}, error) {
-	accountID, ok := params["account_id"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid account ID")
-	}
+func (t *BalanceCheckTool) Parameters() ToolSchema {
+	return NewToolSchema(map[string]ParameterSchema{
+		"account_id": {Type: TypeString, Description: "Account to check; defaults to the selected or primary account"},
+	})
+}
+
+func (t *BalanceCheckTool) CachePolicy() CachePolicy {
+	return CachePolicy{ReadOnly: true, TTL: 30 * time.Second}
+}
+
+func (t *BalanceCheckTool) Execute(ctx context.Context, principal models.Principal, params map[string]interface{}) (interface{}, error) {
+	accountID, _ := params["account_id"].(string)
 
 	// Get balance through agent service
-	balance, err := t.AgentService.GetBalance(accountID)
+	account, err := t.AgentService.GetBalance(principal, accountID)
 	if err != nil {
 		return nil, err
 	}
 
 	return map[string]interface{}{
-		"account_id": accountID,
-		"balance":    balance,
+		"account_id":     account.AccountID,
+		"account_number": account.AccountNumber,
+		"account_type":   account.AccountType,
+		"balance":        account.Balance,
+		"currency":       account.Currency,
 	}, nil
 }
 
@@ -92,7 +131,19 @@ func (t *AddPayeeTool) Description() string {
 	return "Add a new payee/beneficiary"
 }
 
-func (t *AddPayeeTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *AddPayeeTool) Parameters() ToolSchema {
+	return NewToolSchema(map[string]ParameterSchema{
+		"name":           {Type: TypeString, Description: "Payee name"},
+		"account_number": {Type: TypeString, Description: "Payee account number"},
+		"ifsc_code":      {Type: TypeString, Description: "IFSC code of the payee's branch"},
+	}, "name", "account_number")
+}
+
+func (t *AddPayeeTool) CachePolicy() CachePolicy {
+	return CachePolicy{Invalidates: []string{"get_payees"}}
+}
+
+func (t *AddPayeeTool) Execute(ctx context.Context, principal models.Principal, params map[string]interface{}) (interface{}, error) {
 	name, ok := params["name"].(string)
 	if !ok {
 		return nil, fmt.Errorf("invalid payee name")
@@ -103,8 +154,10 @@ func (t *AddPayeeTool) Execute(params map[string]interface{}) (interface{}, erro
 		return nil, fmt.Errorf("invalid account number")
 	}
 
+	ifscCode, _ := params["ifsc_code"].(string)
+
 	// Add payee through agent service
-	payee, err := t.AgentService.AddPayee(name, accountNumber)
+	payee, err := t.AgentService.AddPayee(ctx, principal, name, accountNumber, ifscCode)
 	if err != nil {
 		return nil, err
 	}
@@ -125,7 +178,15 @@ func (t *FixedDepositTool) Description() string {
 	return "Create a fixed deposit"
 }
 
-func (t *FixedDepositTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *FixedDepositTool) Parameters() ToolSchema {
+	return depositSchema("Deposit amount in rupees")
+}
+
+func (t *FixedDepositTool) CachePolicy() CachePolicy {
+	return CachePolicy{Invalidates: []string{"check_balance"}}
+}
+
+func (t *FixedDepositTool) Execute(ctx context.Context, principal models.Principal, params map[string]interface{}) (interface{}, error) {
 	amount, ok := params["amount"].(float64)
 	if !ok {
 		return nil, fmt.Errorf("invalid amount")
@@ -142,7 +203,7 @@ func (t *FixedDepositTool) Execute(params map[string]interface{}) (interface{},
 	}
 
 	// Create FD through agent service
-	fd, err := t.AgentService.CreateFixedDeposit(amount, tenure, tenureUnit)
+	fd, err := t.AgentService.CreateFixedDeposit(ctx, principal, amount, tenure, tenureUnit)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +224,15 @@ func (t *RecurringDepositTool) Description() string {
 	return "Create a recurring deposit"
 }
 
-func (t *RecurringDepositTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *RecurringDepositTool) Parameters() ToolSchema {
+	return depositSchema("Monthly instalment in rupees")
+}
+
+func (t *RecurringDepositTool) CachePolicy() CachePolicy {
+	return CachePolicy{Invalidates: []string{"check_balance"}}
+}
+
+func (t *RecurringDepositTool) Execute(ctx context.Context, principal models.Principal, params map[string]interface{}) (interface{}, error) {
 	amount, ok := params["amount"].(float64)
 	if !ok {
 		return nil, fmt.Errorf("invalid amount")
@@ -180,7 +249,7 @@ func (t *RecurringDepositTool) Execute(params map[string]interface{}) (interface
 	}
 
 	// Create RD through agent service
-	rd, err := t.AgentService.CreateRecurringDeposit(amount, tenure, tenureUnit)
+	rd, err := t.AgentService.CreateRecurringDeposit(ctx, principal, amount, tenure, tenureUnit)
 	if err != nil {
 		return nil, err
 	}
@@ -201,7 +270,22 @@ func (t *InterestRatesTool) Description() string {
 	return "Get current interest rates for various products"
 }
 
-func (t *InterestRatesTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *InterestRatesTool) Parameters() ToolSchema {
+	return NewToolSchema(map[string]ParameterSchema{
+		"product_type": {
+			Type:        TypeString,
+			Description: "Product to get the rate for",
+			Enum:        []string{"all", "fd", "rd", "personal_loan", "home_loan", "car_loan"},
+			Default:     "all",
+		},
+	})
+}
+
+func (t *InterestRatesTool) CachePolicy() CachePolicy {
+	return CachePolicy{ReadOnly: true, TTL: time.Hour}
+}
+
+func (t *InterestRatesTool) Execute(ctx context.Context, principal models.Principal, params map[string]interface{}) (interface{}, error) {
 	productType, ok := params["product_type"].(string)
 	if !ok {
 		productType = "all" // Default to all products
@@ -219,7 +303,6 @@ func (t *InterestRatesTool) Execute(params map[string]interface{}) (interface{},
 // Weather Tool
 type WeatherTool struct {
 	CacheTTL time.Duration
-	Cache    map[string]interface{}
 }
 
 func (t *WeatherTool) Name() string {
@@ -230,17 +313,22 @@ func (t *WeatherTool) Description() string {
 	return "Get weather information for a location"
 }
 
-func (t *WeatherTool) Execute(params map[string]interface{}) (interface{}, error) {
+func (t *WeatherTool) Parameters() ToolSchema {
+	return NewToolSchema(map[string]ParameterSchema{
+		"location": {Type: TypeString, Description: "City or place name"},
+	}, "location")
+}
+
+func (t *WeatherTool) CachePolicy() CachePolicy {
+	return CachePolicy{ReadOnly: true, TTL: t.CacheTTL}
+}
+
+func (t *WeatherTool) Execute(ctx context.Context, principal models.Principal, params map[string]interface{}) (interface{}, error) {
 	location, ok := params["location"].(string)
 	if !ok {
 		return nil, fmt.Errorf("invalid location")
 	}
 
-	// Check cache first
-	if cached, exists := t.Cache[location]; exists {
-		return cached, nil
-	}
-
 	// Simulate weather API call
 	weather := map[string]interface{}{
 		"location":  location,
@@ -249,8 +337,5 @@ func (t *WeatherTool) Execute(params map[string]interface{}) (interface{}, error
 		"humidity":  65,
 	}
 
-	// Cache the result
-	t.Cache[location] = weather
-
 	return weather, nil
 }
@@ -0,0 +1,743 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+// Chat event types emitted while a turn is orchestrated
+const (
+	EventAgent        = "agent"
+	EventToolCall     = "tool_call"
+	EventToolResult   = "tool_result"
+	EventAgentData    = "agent_data"
+	EventStep         = "step"
+	EventConfirmation = "confirmation"
+)
+
+// ChatEvent is a structured step of a chat turn, forwarded to the client alongside the text stream
+type ChatEvent struct {
+	Type string
+	Data interface{}
+}
+
+// ChatEventSink receives orchestration events as they happen
+type ChatEventSink func(event ChatEvent)
+
+// ToolCall records a tool invocation made while handling a turn
+type ToolCall struct {
+	Name       string                 `json:"name"`
+	Params     map[string]interface{} `json:"params"`
+	Result     interface{}            `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Validation []FieldError           `json:"validation,omitempty"`
+}
+
+// TurnResult summarises how a single user message was handled
+type TurnResult struct {
+	Intent       *Intent
+	Parameters   map[string]interface{}
+	Response     *models.AgentResponse
+	ToolCalls    []ToolCall
+	Step         *models.ConversationStep
+	Confirmation *models.PendingAction
+	// ChangedData is set once an operation with side effects ran; such a turn is never run again
+	ChangedData bool
+}
+
+// ChatResponse describes the turn as a single synchronous reply with the given content
+func (t *TurnResult) ChatResponse(sessionID string, content string) models.ChatResponse {
+	response := models.ChatResponse{
+		Response:     content,
+		SessionID:    sessionID,
+		Tools:        []string{},
+		NextActions:  []string{},
+		Entities:     map[string]interface{}{},
+		Confirmation: t.Confirmation,
+	}
+	if t.Intent != nil {
+		response.Intent = t.Intent.Name
+		response.Confidence = t.Intent.Confidence
+		if t.Intent.Entities != nil {
+			response.Entities = t.Intent.Entities
+		}
+	}
+	if t.Step != nil {
+		// A continued flow keeps the intent it started with
+		response.Intent = t.Step.Intent
+	}
+	for _, call := range t.ToolCalls {
+		response.Tools = append(response.Tools, call.Name)
+	}
+	if t.Response != nil {
+		response.AgentUsed = t.Response.AgentName
+		response.AgentData = t.Response.Data
+		response.RequiresInput = t.Response.RequiresInput
+		response.MissingParameters = t.Response.MissingParameters
+		if t.Response.Actions != nil {
+			response.NextActions = t.Response.Actions
+		}
+	}
+	return response
+}
+
+// ChatOrchestrator runs a chat turn through intent recognition, agent selection,
+// parameter extraction and tool execution, and only then asks the LLM to phrase
+// the grounded result.
+type ChatOrchestrator struct {
+	agentService        *AgentService
+	conversationService *ConversationService
+	intents             *IntentCatalog
+	llamaService        *LlamaService
+	assembler           *PromptAssembler
+	toolRegistry        *ToolRegistry
+	dialogueState       *DialogueStateService
+	pendingActions      *PendingActionService
+	maxToolRounds       int
+
+	summaryMu   sync.Mutex
+	summarizing map[string]bool // Conversations whose summary is being written
+}
+
+// functionCallingAgent names responses produced by the model choosing tools itself
+const functionCallingAgent = "FunctionCalling"
+
+func NewChatOrchestrator(
+	agentService *AgentService,
+	conversationService *ConversationService,
+	intents *IntentCatalog,
+	llamaService *LlamaService,
+	assembler *PromptAssembler,
+	toolRegistry *ToolRegistry,
+	dialogueState *DialogueStateService,
+	pendingActions *PendingActionService,
+	maxToolRounds int,
+) *ChatOrchestrator {
+	return &ChatOrchestrator{
+		agentService:        agentService,
+		conversationService: conversationService,
+		intents:             intents,
+		llamaService:        llamaService,
+		assembler:           assembler,
+		toolRegistry:        toolRegistry,
+		dialogueState:       dialogueState,
+		pendingActions:      pendingActions,
+		maxToolRounds:       maxToolRounds,
+		summarizing:         make(map[string]bool),
+	}
+}
+
+// ProcessMessage handles one user message and streams the assistant reply into streamingSession.
+// Structured progress (agent, tool calls, agent data) is reported through emit.
+func (o *ChatOrchestrator) ProcessMessage(ctx context.Context, session *models.UserSession, message string, streamingSession *models.StreamingSession, emit ChatEventSink) *TurnResult {
+	conversation, history := o.snapshot(session)
+	o.checkpoint(session)
+	defer o.summarize(session.ID)
+
+	// 1. Intent
+	intent := o.intents.RecognizeIntent(message)
+	log.Printf("[Orchestrator] Intent: %s (confidence %.2f)", intent.Name, intent.Confidence)
+
+	// An action waiting for confirmation is settled before anything else
+	if result := o.settlePending(ctx, session, message, intent, history, streamingSession, emit); result != nil {
+		return result
+	}
+
+	if o.dialogueState.IsReset(message) {
+		return o.reset(session, message, intent, streamingSession)
+	}
+
+	// 2. Parameters: either continue the pending step or start from this message
+	step := o.dialogueState.GetPendingStep(session)
+	if step != nil && step.Handoff != nil && o.pendingActions.IsConfirmation(message, "") {
+		log.Printf("[Orchestrator] Handing step %s (%s) off to %s", step.StepID, step.AgentName, step.Handoff.Agent)
+		step = o.dialogueState.HandOff(session, step)
+	} else if step != nil {
+		owner := o.agentService.GetAgentByName(step.AgentName)
+		filled := o.dialogueState.Merge(step, owner.GetRequiredParameters(), message)
+		if len(filled) == 0 && o.startsNewFlow(intent, step) {
+			log.Printf("[Orchestrator] Abandoning step %s (%s) for new intent %s", step.StepID, step.Intent, intent.Name)
+			o.dialogueState.Clear(session)
+			step = nil
+		} else {
+			log.Printf("[Orchestrator] Continuing step %s with %v", step.StepID, filled)
+		}
+	}
+
+	intentName := intent.Name
+	var params map[string]interface{}
+	if step != nil {
+		intentName = step.Intent
+		params = step.Parameters
+	} else {
+		params = o.extractParameters(intent, message)
+	}
+
+	if err := o.conversationService.AddMessage(session.ID, "user", message, intentName, nil, intent.Entities, ""); err != nil {
+		log.Printf("[Orchestrator] Error adding user message: %v", err)
+	}
+
+	// 3. Agent
+	principal := session.Principal()
+	agentCtx := &models.AgentContext{
+		SessionID:    session.ID,
+		UserID:       principal.UserID,
+		AccountID:    principal.AccountID,
+		Message:      message,
+		Intent:       intentName,
+		Entities:     intent.Entities,
+		Parameters:   params,
+		Conversation: &models.Conversation{ID: conversation.ID, Messages: history},
+		CurrentStep:  step,
+		Confidence:   intent.Confidence,
+	}
+
+	var response *models.AgentResponse
+	if step != nil {
+		response = o.agentService.ProcessWithNamedAgent(ctx, step.AgentName, agentCtx)
+	} else {
+		response = o.agentService.ProcessWithAgent(ctx, agentCtx)
+	}
+	agentEvent := map[string]interface{}{
+		"agent":      response.AgentName,
+		"intent":     intentName,
+		"confidence": intent.Confidence,
+	}
+	if response.Ranking != nil {
+		agentEvent["ranking"] = response.Ranking
+		agentEvent["clarify"] = response.Clarify
+	}
+	emit(ChatEvent{Type: EventAgent, Data: agentEvent})
+
+	step = o.dialogueState.Track(session, step, intentName, params, response)
+	o.emitStep(step, emit)
+
+	result := &TurnResult{
+		Intent:     intent,
+		Parameters: params,
+		Response:   response,
+		Step:       step,
+	}
+
+	// Requests no agent recognised are left to the model and the tools it may call
+	if o.maxToolRounds > 0 && o.agentService.IsFallback(response.AgentName) && o.callFunctions(ctx, session, intent, agentCtx, result, streamingSession, emit) {
+		return result
+	}
+
+	// High-risk operations stop here until the user confirms them
+	if response.RequiresConfirmation || (response.RequiresTool && o.pendingActions.IsHighRisk(response.ToolName)) {
+		result.Confirmation = o.requestConfirmation(session, intentName, params, response, emit)
+	}
+
+	o.complete(ctx, session, intent, agentCtx, result, streamingSession, emit)
+	return result
+}
+
+// ConfirmAction executes the pending action identified by confirmationID on behalf of the session owner
+func (o *ChatOrchestrator) ConfirmAction(ctx context.Context, session *models.UserSession, confirmationID string, streamingSession *models.StreamingSession, emit ChatEventSink) (*TurnResult, error) {
+	action, err := o.pendingActions.Confirm(session.ID, confirmationID)
+	if err != nil {
+		return nil, err
+	}
+	_, history := o.snapshot(session)
+	defer o.summarize(session.ID)
+	return o.executeConfirmed(ctx, session, action, "confirm", history, streamingSession, emit), nil
+}
+
+// CancelAction discards the pending action identified by confirmationID
+func (o *ChatOrchestrator) CancelAction(session *models.UserSession, confirmationID string) (*models.PendingAction, error) {
+	action, err := o.pendingActions.Cancel(session.ID, confirmationID)
+	if err != nil {
+		return nil, err
+	}
+	o.conversationService.GetOrCreateConversation(session.ID)
+	o.conversationService.AddMessage(session.ID, "assistant", "Okay, I've cancelled that. Nothing was changed.", action.Intent, nil, nil, action.AgentName)
+	return action, nil
+}
+
+// GetPendingAction returns the action of the session that is waiting for confirmation
+func (o *ChatOrchestrator) GetPendingAction(session *models.UserSession) (*models.PendingAction, error) {
+	return o.pendingActions.GetForSession(session.ID)
+}
+
+// snapshot returns the session conversation and a copy of its messages taken before this turn
+func (o *ChatOrchestrator) snapshot(session *models.UserSession) (*models.Conversation, []models.Message) {
+	conversation := o.conversationService.GetOrCreateConversation(session.ID)
+	history := make([]models.Message, len(conversation.Messages))
+	copy(history, conversation.Messages)
+	return conversation, history
+}
+
+// settlePending handles replies to an outstanding confirmation. It returns nil
+// when the message has nothing to do with one and should be processed normally.
+func (o *ChatOrchestrator) settlePending(ctx context.Context, session *models.UserSession, message string, intent *Intent, history []models.Message, streamingSession *models.StreamingSession, emit ChatEventSink) *TurnResult {
+	pending, err := o.pendingActions.GetForSession(session.ID)
+	var pendingID string
+	if err == nil {
+		pendingID = pending.ID
+	}
+	confirming := o.pendingActions.IsConfirmation(message, pendingID)
+
+	switch {
+	case err == nil && confirming:
+		action, err := o.pendingActions.Confirm(session.ID, pending.ID)
+		if err != nil {
+			return o.reply(session, message, intent, pending.AgentName, "That request has expired and nothing was changed. Please start it again.", streamingSession)
+		}
+		return o.executeConfirmed(ctx, session, action, message, history, streamingSession, emit)
+	case err == nil && (o.pendingActions.IsDecline(message, pendingID) || o.dialogueState.IsReset(message)):
+		o.pendingActions.Cancel(session.ID, pending.ID)
+		emit(ChatEvent{Type: EventConfirmation, Data: map[string]interface{}{
+			"confirmation_id": pending.ID,
+			"status":          "cancelled",
+		}})
+		return o.reply(session, message, intent, pending.AgentName, "Okay, I've cancelled that. Nothing was changed.", streamingSession)
+	case err == nil:
+		// The user moved on; a later "yes" must not approve an action they never looked at again
+		log.Printf("[Orchestrator] Dropping unconfirmed %s (%s)", pending.ID, pending.ToolName)
+		o.pendingActions.Cancel(session.ID, pending.ID)
+	case errors.Is(err, ErrConfirmationExpired) && confirming:
+		return o.reply(session, message, intent, "", "That request has expired and nothing was changed. Please start it again.", streamingSession)
+	case confirming && o.dialogueState.GetPendingStep(session) == nil:
+		return o.reply(session, message, intent, "", "There's nothing waiting for your confirmation. How can I help you?", streamingSession)
+	}
+	return nil
+}
+
+// requestConfirmation parks a high-risk operation and turns the response into a confirmation prompt
+func (o *ChatOrchestrator) requestConfirmation(session *models.UserSession, intentName string, params map[string]interface{}, response *models.AgentResponse, emit ChatEventSink) *models.PendingAction {
+	action := o.pendingActions.Create(&models.PendingAction{
+		SessionID:  session.ID,
+		UserID:     session.GetAccountID(),
+		AccountID:  session.Principal().AccountID,
+		AgentName:  response.AgentName,
+		Intent:     intentName,
+		ToolName:   response.ToolName,
+		Parameters: params,
+		ToolParams: response.ToolParams,
+		Summary:    strings.TrimSuffix(response.Message, "."),
+	})
+	emit(ChatEvent{Type: EventConfirmation, Data: action})
+
+	response.RequiresConfirmation = true
+	response.RequiresTool = false
+	response.Message = fmt.Sprintf("Please confirm: %s.\nReply 'confirm' to proceed or 'cancel' to stop.", action.Summary)
+	return action
+}
+
+// SelectAccount makes one of the session user's accounts the one banking tools act on
+func (o *ChatOrchestrator) SelectAccount(session *models.UserSession, accountID string) error {
+	return o.agentService.SelectAccount(session, accountID)
+}
+
+// executeConfirmed replays the owning agent of a confirmed action and carries out the operation
+func (o *ChatOrchestrator) executeConfirmed(ctx context.Context, session *models.UserSession, action *models.PendingAction, message string, history []models.Message, streamingSession *models.StreamingSession, emit ChatEventSink) *TurnResult {
+	log.Printf("[Orchestrator] Executing confirmed %s (%s)", action.ID, action.ToolName)
+	emit(ChatEvent{Type: EventConfirmation, Data: map[string]interface{}{
+		"confirmation_id": action.ID,
+		"status":          "confirmed",
+	}})
+
+	intent := &Intent{Name: action.Intent, Confidence: 1.0, Entities: make(map[string]interface{})}
+	if err := o.conversationService.AddMessage(session.ID, "user", message, action.Intent, nil, nil, ""); err != nil {
+		log.Printf("[Orchestrator] Error adding user message: %v", err)
+	}
+
+	agentCtx := &models.AgentContext{
+		SessionID:    session.ID,
+		UserID:       action.UserID,
+		AccountID:    action.AccountID,
+		Message:      message,
+		Intent:       action.Intent,
+		Entities:     intent.Entities,
+		Parameters:   action.Parameters,
+		Conversation: &models.Conversation{Messages: history},
+		Confidence:   1.0,
+		Confirmed:    true,
+	}
+	var response *models.AgentResponse
+	if action.AgentName == functionCallingAgent {
+		// No agent owns a call the model chose, so the validated tool call itself is replayed
+		response = &models.AgentResponse{
+			Message:      action.Summary,
+			AgentName:    functionCallingAgent,
+			RequiresTool: true,
+			ToolName:     action.ToolName,
+			ToolParams:   action.ToolParams,
+		}
+	} else {
+		response = o.agentService.ProcessWithNamedAgent(ctx, action.AgentName, agentCtx)
+		// Only the exact operation the user approved is ever carried out
+		if response.RequiresTool && !sameToolCall(response.ToolName, response.ToolParams, action.ToolName, action.ToolParams) {
+			log.Printf("[Orchestrator] Not executing %s: the agent now asks for %s %v, not the confirmed %s %v", action.ID, response.ToolName, response.ToolParams, action.ToolName, action.ToolParams)
+			response = &models.AgentResponse{
+				Message:   "The details of that request changed after you confirmed it, so nothing was done. Please start it again.",
+				AgentName: action.AgentName,
+				Data:      map[string]interface{}{"error": "confirmed operation changed"},
+			}
+		}
+	}
+	emit(ChatEvent{Type: EventAgent, Data: map[string]interface{}{
+		"agent":      response.AgentName,
+		"intent":     action.Intent,
+		"confidence": 1.0,
+	}})
+
+	result := &TurnResult{
+		Intent:     intent,
+		Parameters: action.Parameters,
+		Response:   response,
+		// Agents may act on a confirmed request themselves, so it counts as a change even before the tool runs
+		ChangedData: true,
+	}
+	o.complete(ctx, session, intent, agentCtx, result, streamingSession, emit)
+
+	// Agents may have changed data without going through the registry
+	o.toolRegistry.InvalidateDependents(agentCtx.UserID, action.ToolName)
+	return result
+}
+
+// sameToolCall reports whether two tool calls have the same name and parameters,
+// compared as JSON so that numbers of different Go types still match
+func sameToolCall(name string, params map[string]interface{}, otherName string, otherParams map[string]interface{}) bool {
+	if name != otherName {
+		return false
+	}
+	a, errA := json.Marshal(params)
+	b, errB := json.Marshal(otherParams)
+	return errA == nil && errB == nil && string(a) == string(b)
+}
+
+// complete runs the tool the agent asked for, reports its data and streams the reply
+func (o *ChatOrchestrator) complete(ctx context.Context, session *models.UserSession, intent *Intent, agentCtx *models.AgentContext, result *TurnResult, streamingSession *models.StreamingSession, emit ChatEventSink) {
+	response := result.Response
+
+	// 4. Tools
+	if response.RequiresTool && response.ToolName != "" {
+		call, err := o.executeTool(ctx, agentCtx.Principal(), response.ToolName, response.ToolParams, emit)
+		result.ToolCalls = append(result.ToolCalls, call)
+
+		var validationErr *ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			response = o.askAgain(ctx, session, agentCtx, result, validationErr, emit)
+		case err != nil:
+			response.Message = fmt.Sprintf("The %s operation failed: %s", call.Name, call.Error)
+			response.Data = map[string]interface{}{"error": call.Error}
+		default:
+			response.Data = call.Result
+			if !o.toolRegistry.IsReadOnly(call.Name) {
+				result.ChangedData = true
+			}
+		}
+	}
+
+	if response.Data != nil {
+		emit(ChatEvent{Type: EventAgentData, Data: map[string]interface{}{
+			"agent": response.AgentName,
+			"data":  response.Data,
+		}})
+	}
+
+	// 5. Phrase the result
+	promptVersion := o.respond(ctx, session, agentCtx.Message, intent, agentCtx.Conversation.Messages, response, streamingSession)
+
+	o.conversationService.AppendMessage(session.ID, models.Message{
+		Role:          "assistant",
+		Content:       streamingSession.GetContent(),
+		Intent:        agentCtx.Intent,
+		Actions:       response.Actions,
+		AgentName:     response.AgentName,
+		Response:      response,
+		ChangedData:   result.ChangedData,
+		PromptVersion: promptVersion,
+	})
+}
+
+// callFunctions offers the registered tools to the model and feeds every result back
+// until it answers. Tools still run through ToolRegistry.ExecuteTool, and high-risk
+// ones are parked for confirmation instead. It returns false when the model cannot be
+// reached, leaving the caller to reply the usual way.
+func (o *ChatOrchestrator) callFunctions(ctx context.Context, session *models.UserSession, intent *Intent, agentCtx *models.AgentContext, result *TurnResult, streamingSession *models.StreamingSession, emit ChatEventSink) bool {
+	tools := o.toolRegistry.Definitions()
+	promptCtx := o.promptContext(session, agentCtx.Message, agentCtx.Conversation.Messages)
+	promptCtx.AgentName = functionCallingAgent
+	messages := o.llamaService.BuildChatMessages(promptCtx)
+
+	for round := 0; ; round++ {
+		offered := tools
+		if round >= o.maxToolRounds {
+			offered = nil // Out of rounds: the model has to answer with what it has
+		}
+
+		reply, err := o.llamaService.ChatWithTools(ctx, messages, offered)
+		if err != nil && ctx.Err() != nil {
+			log.Printf("[Orchestrator] Turn abandoned: %v", ctx.Err())
+			streamingSession.MarkDone()
+			if result.ChangedData {
+				// Whatever was said, the history has to show that this turn changed data
+				o.conversationService.AppendMessage(session.ID, models.Message{
+					Role:        "assistant",
+					Content:     streamingSession.GetContent(),
+					Intent:      agentCtx.Intent,
+					AgentName:   functionCallingAgent,
+					ChangedData: true,
+				})
+			}
+			return true
+		}
+		if err != nil {
+			log.Printf("[Orchestrator] Function calling unavailable: %v", err)
+			return false
+		}
+		messages = append(messages, *reply)
+
+		if len(reply.ToolCalls) == 0 {
+			response := &models.AgentResponse{Message: reply.Content, AgentName: functionCallingAgent}
+			if n := len(result.ToolCalls); n > 0 {
+				response.Data = result.ToolCalls[n-1].Result
+				emit(ChatEvent{Type: EventAgentData, Data: map[string]interface{}{
+					"agent": response.AgentName,
+					"data":  response.Data,
+				}})
+			}
+			result.Response = response
+
+			streamingSession.AppendContent(reply.Content)
+			streamingSession.MarkDone()
+			o.conversationService.AppendMessage(session.ID, models.Message{
+				Role:          "assistant",
+				Content:       reply.Content,
+				Intent:        agentCtx.Intent,
+				AgentName:     response.AgentName,
+				Response:      response,
+				ChangedData:   result.ChangedData,
+				PromptVersion: o.llamaService.PromptVersion(),
+			})
+			return true
+		}
+
+		for _, toolCall := range reply.ToolCalls {
+			name, args := toolCall.Function.Name, map[string]interface{}(toolCall.Function.Arguments)
+			log.Printf("[Orchestrator] Model called %s (round %d)", name, round+1)
+
+			if o.pendingActions.IsHighRisk(name) {
+				params, err := o.toolRegistry.ValidateParameters(name, args)
+				if err != nil {
+					messages = append(messages, toolMessage(toolCall.ID, ToolCall{Name: name, Params: args, Error: err.Error()}))
+					continue
+				}
+				result.Response = &models.AgentResponse{
+					Message:      describeToolCall(name, params),
+					AgentName:    functionCallingAgent,
+					RequiresTool: true,
+					ToolName:     name,
+					ToolParams:   params,
+				}
+				result.Confirmation = o.requestConfirmation(session, agentCtx.Intent, agentCtx.Parameters, result.Response, emit)
+				o.complete(ctx, session, intent, agentCtx, result, streamingSession, emit)
+				return true
+			}
+
+			call, err := o.executeTool(ctx, agentCtx.Principal(), name, args, emit)
+			result.ToolCalls = append(result.ToolCalls, call)
+			if err == nil && !o.toolRegistry.IsReadOnly(name) {
+				result.ChangedData = true
+			}
+			messages = append(messages, toolMessage(toolCall.ID, call))
+		}
+	}
+}
+
+// toolMessage reports a tool outcome back to the model as the answer to the call with the given ID
+func toolMessage(callID string, call ToolCall) ChatMessage {
+	var payload interface{} = call.Result
+	if call.Error != "" {
+		payload = map[string]interface{}{"error": call.Error, "validation": call.Validation}
+	}
+	content, err := json.Marshal(payload)
+	if err != nil {
+		content = []byte(fmt.Sprintf(`{"error": %q}`, err.Error()))
+	}
+	return ChatMessage{Role: "tool", Content: string(content), ToolName: call.Name, ToolCallID: callID}
+}
+
+// describeToolCall summarises a call the model made, for the confirmation prompt
+func describeToolCall(name string, params map[string]interface{}) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	details := make([]string, len(keys))
+	for i, key := range keys {
+		details[i] = fmt.Sprintf("%s %v", strings.ReplaceAll(key, "_", " "), params[key])
+	}
+	return fmt.Sprintf("%s with %s", strings.ReplaceAll(name, "_", " "), strings.Join(details, ", "))
+}
+
+// askAgain turns rejected tool parameters into a follow-up question. The rejected
+// values are dropped and the owning agent is consulted again, so the user hears the
+// agent's own question for the missing parameter and the answer resumes the step.
+func (o *ChatOrchestrator) askAgain(ctx context.Context, session *models.UserSession, agentCtx *models.AgentContext, result *TurnResult, validationErr *ValidationError, emit ChatEventSink) *models.AgentResponse {
+	log.Printf("[Orchestrator] %v", validationErr)
+	for _, name := range validationErr.Parameters() {
+		delete(agentCtx.Parameters, name)
+	}
+	agentCtx.Confirmed = false
+
+	response := o.agentService.ProcessWithNamedAgent(ctx, result.Response.AgentName, agentCtx)
+	if !response.RequiresInput {
+		field := validationErr.Fields[0]
+		response = &models.AgentResponse{
+			Message:           fmt.Sprintf("I couldn't use that: %s. Could you give me the %s again?", field.Message, strings.ReplaceAll(field.Parameter, "_", " ")),
+			AgentName:         result.Response.AgentName,
+			RequiresInput:     true,
+			MissingParameters: validationErr.Parameters(),
+		}
+	}
+
+	result.Response = response
+	result.Step = o.dialogueState.Track(session, result.Step, agentCtx.Intent, agentCtx.Parameters, response)
+	o.emitStep(result.Step, emit)
+	return response
+}
+
+func (o *ChatOrchestrator) emitStep(step *models.ConversationStep, emit ChatEventSink) {
+	if step == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"step_id":    step.StepID,
+		"agent":      step.AgentName,
+		"intent":     step.Intent,
+		"parameters": step.Parameters,
+		"missing":    step.Missing,
+		"complete":   step.Complete,
+	}
+	if step.Handoff != nil {
+		data["handoff"] = step.Handoff
+	}
+	emit(ChatEvent{Type: EventStep, Data: data})
+}
+
+// reset abandons the pending step, if any, at the user's request
+func (o *ChatOrchestrator) reset(session *models.UserSession, message string, intent *Intent, streamingSession *models.StreamingSession) *TurnResult {
+	if step := o.dialogueState.GetPendingStep(session); step != nil {
+		o.dialogueState.Clear(session)
+		return o.reply(session, message, intent, step.AgentName, "Okay, I've cancelled that. What would you like to do next?", streamingSession)
+	}
+	return o.reply(session, message, intent, "", "There's nothing in progress to cancel. How can I help you?", streamingSession)
+}
+
+// reply answers with a fixed message, bypassing agents and the LLM
+func (o *ChatOrchestrator) reply(session *models.UserSession, message string, intent *Intent, agentName string, text string, streamingSession *models.StreamingSession) *TurnResult {
+	if agentName == "" {
+		agentName = o.agentService.GetAgentByName("").GetName()
+	}
+	response := &models.AgentResponse{Message: text, AgentName: agentName}
+
+	o.conversationService.AddMessage(session.ID, "user", message, intent.Name, nil, intent.Entities, "")
+	streamingSession.AppendContent(response.Message)
+	streamingSession.MarkDone()
+	o.conversationService.AddMessage(session.ID, "assistant", response.Message, intent.Name, nil, nil, response.AgentName)
+
+	return &TurnResult{Intent: intent, Response: response}
+}
+
+// startsNewFlow reports whether a message that answered nothing in the pending
+// step is instead a confident request for something else
+func (o *ChatOrchestrator) startsNewFlow(intent *Intent, step *models.ConversationStep) bool {
+	return intent.Name != generalQueryIntent && intent.Name != step.Intent && intent.Confidence >= 1.0
+}
+
+// respond streams the user-facing reply. Follow-up questions, clarifications and confirmation prompts
+// are sent verbatim; everything else is phrased by the LLM from the agent's verified result. When the
+// LLM cannot be used the agent's own message is sent instead, after an error event saying why. It
+// returns the version of the prompt templates the LLM was given, or "" for a verbatim reply.
+func (o *ChatOrchestrator) respond(ctx context.Context, session *models.UserSession, message string, intent *Intent, history []models.Message, response *models.AgentResponse, streamingSession *models.StreamingSession) string {
+	if response.RequiresInput || response.RequiresConfirmation || response.Clarify {
+		streamingSession.AppendContent(response.Message)
+		streamingSession.MarkDone()
+		return ""
+	}
+
+	promptCtx := o.promptContext(session, message, history)
+	promptCtx.Intent = &models.Intent{
+		Name:       intent.Name,
+		Confidence: intent.Confidence,
+		Entities:   intent.Entities,
+	}
+	promptCtx.AgentName = response.AgentName
+	if !o.agentService.IsFallback(response.AgentName) {
+		promptCtx.AgentResponse = response
+	}
+
+	defer streamingSession.MarkDone()
+	if err := o.llamaService.StreamReply(ctx, o.llamaService.BuildReplyMessages(promptCtx), streamingSession); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[Orchestrator] Answering with the %s message alone", response.AgentName)
+			streamingSession.AppendContent(response.Message)
+		}
+		return ""
+	}
+	return o.llamaService.PromptVersion()
+}
+
+func (o *ChatOrchestrator) executeTool(ctx context.Context, principal models.Principal, name string, params map[string]interface{}, emit ChatEventSink) (ToolCall, error) {
+	call := ToolCall{Name: name, Params: params}
+	emit(ChatEvent{Type: EventToolCall, Data: call})
+
+	data, err := o.toolRegistry.ExecuteTool(ctx, principal, name, params)
+	if err != nil {
+		log.Printf("[Orchestrator] Tool %s failed: %v", name, err)
+		call.Error = err.Error()
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			call.Validation = validationErr.Fields
+		}
+	} else {
+		call.Result = data
+	}
+
+	emit(ChatEvent{Type: EventToolResult, Data: call})
+	return call, err
+}
+
+// intentSlots lists the parameters worth looking for in the first message of a flow
+var intentSlots = map[string][]string{
+	"fund_transfer": {"amount", "method"},
+	"add_payee":     {"account_number", "ifsc_code"},
+	"create_fd":     {"amount", "tenure"},
+	"create_rd":     {"amount", "tenure"},
+}
+
+// extractParameters maps recognised entities onto the parameter names the agents expect
+func (o *ChatOrchestrator) extractParameters(intent *Intent, message string) map[string]interface{} {
+	params := make(map[string]interface{})
+
+	if names, exists := intentSlots[intent.Name]; exists {
+		slots := make(map[string]bool)
+		for _, name := range names {
+			slots[name] = true
+		}
+		for key, value := range extractSlotValues(message, slots, "") {
+			params[key] = value
+		}
+	}
+
+	for key, value := range intent.Entities {
+		params[key] = value
+	}
+
+	return params
+}
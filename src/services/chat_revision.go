@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+var (
+	ErrNothingToRegenerate = errors.New("there is no reply to regenerate")
+	ErrNothingToEdit       = errors.New("there is no message to edit")
+	ErrTurnChangedData     = errors.New("the last message already changed account data and cannot be edited")
+)
+
+// turnStartStepKey is the conversation context entry holding the dialogue step as it
+// was before the latest turn, so that the turn can be rewound
+const turnStartStepKey = "turn_start_step"
+
+// checkpoint remembers the dialogue step the session is in before a turn starts
+func (o *ChatOrchestrator) checkpoint(session *models.UserSession) {
+	o.conversationService.UpdateContext(session.ID, turnStartStepKey, session.GetCurrentStep().Clone())
+}
+
+// rewind drops the messages of the latest turn from index on and puts the dialogue
+// back where it was before the turn. A confirmation the turn asked for is withdrawn.
+func (o *ChatOrchestrator) rewind(session *models.UserSession, index int) {
+	o.conversationService.TruncateConversation(session.ID, index)
+
+	step, _ := o.conversationService.GetContext(session.ID, turnStartStepKey)
+	before, _ := step.(*models.ConversationStep)
+	o.dialogueState.Restore(session, before)
+
+	if pending, err := o.pendingActions.GetForSession(session.ID); err == nil {
+		log.Printf("[Orchestrator] Withdrawing %s (%s) of the rewound turn", pending.ID, pending.ToolName)
+		o.pendingActions.Cancel(session.ID, pending.ID)
+	}
+}
+
+// Regenerate replaces the last assistant reply with a newly phrased one. The reply
+// is phrased again from the agent result saved with it, so no agent or tool runs a
+// second time and an operation the turn carried out is never repeated. A last user
+// message that never got a reply, for instance because it was cancelled before any
+// operation ran, is processed again instead.
+func (o *ChatOrchestrator) Regenerate(ctx context.Context, session *models.UserSession, streamingSession *models.StreamingSession, emit ChatEventSink) (*TurnResult, error) {
+	_, history := o.snapshot(session)
+	if len(history) == 0 {
+		return nil, ErrNothingToRegenerate
+	}
+
+	last := len(history) - 1
+	if history[last].Role == "user" {
+		log.Printf("[Orchestrator] Retrying unanswered message %s", history[last].ID)
+		o.rewind(session, last)
+		return o.ProcessMessage(ctx, session, history[last].Content, streamingSession, emit), nil
+	}
+
+	reply := history[last]
+	prompt, start := models.Message{}, last
+	for i := last - 1; i >= 0; i-- {
+		if history[i].Role == "user" {
+			prompt, start = history[i], i
+			break
+		}
+	}
+	log.Printf("[Orchestrator] Regenerating reply %s", reply.ID)
+	o.conversationService.TruncateConversation(session.ID, last)
+
+	response := reply.Response
+	if response == nil {
+		response = &models.AgentResponse{Message: reply.Content, AgentName: reply.AgentName}
+	}
+	intent := &Intent{Name: reply.Intent, Confidence: 1.0, Entities: prompt.Entities}
+	emit(ChatEvent{Type: EventAgent, Data: map[string]interface{}{
+		"agent":       response.AgentName,
+		"intent":      reply.Intent,
+		"confidence":  1.0,
+		"regenerated": reply.ID,
+	}})
+	if response.Data != nil {
+		emit(ChatEvent{Type: EventAgentData, Data: map[string]interface{}{
+			"agent": response.AgentName,
+			"data":  response.Data,
+		}})
+	}
+
+	promptVersion := o.respond(ctx, session, prompt.Content, intent, history[:start], response, streamingSession)
+	o.conversationService.AppendMessage(session.ID, models.Message{
+		Role:          "assistant",
+		Content:       streamingSession.GetContent(),
+		Intent:        reply.Intent,
+		Actions:       reply.Actions,
+		AgentName:     reply.AgentName,
+		Response:      response,
+		ChangedData:   reply.ChangedData,
+		PromptVersion: promptVersion,
+	})
+
+	return &TurnResult{Intent: intent, Response: response, ChangedData: reply.ChangedData}, nil
+}
+
+// EditLastMessage replaces the last user message and processes the conversation again
+// from there. A turn that changed account data cannot be undone, so it cannot be edited.
+func (o *ChatOrchestrator) EditLastMessage(ctx context.Context, session *models.UserSession, message string, streamingSession *models.StreamingSession, emit ChatEventSink) (*TurnResult, error) {
+	_, history := o.snapshot(session)
+
+	index := -1
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == "user" {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, ErrNothingToEdit
+	}
+	for _, msg := range history[index+1:] {
+		if msg.ChangedData {
+			return nil, ErrTurnChangedData
+		}
+	}
+
+	log.Printf("[Orchestrator] Editing message %s", history[index].ID)
+	o.rewind(session, index)
+	return o.ProcessMessage(ctx, session, message, streamingSession, emit), nil
+}
@@ -6,10 +6,12 @@ import (
 )
 
 type ConversationMemory struct {
-	mu       sync.RWMutex
-	messages []Message
-	entities map[string]interface{}
-	context  map[string]interface{}
+	mu         sync.RWMutex
+	messages   []Message
+	entities   map[string]interface{}
+	context    map[string]interface{}
+	summary    string // Rolling summary of the oldest messages of the conversation
+	summarized int    // How many messages, counted from the start, the summary covers
 }
 
 type Message struct {
@@ -78,10 +80,27 @@ func (cm *ConversationMemory) GetContext(key string) (interface{}, bool) {
 	return val, exists
 }
 
+// GetSummary returns the rolling summary and how many messages it covers
+func (cm *ConversationMemory) GetSummary() (string, int) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.summary, cm.summarized
+}
+
+// SetSummary replaces the rolling summary, which covers the first covered messages
+func (cm *ConversationMemory) SetSummary(summary string, covered int) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.summary = summary
+	cm.summarized = covered
+}
+
 func (cm *ConversationMemory) Clear() {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 	cm.messages = make([]Message, 0)
 	cm.entities = make(map[string]interface{})
 	cm.context = make(map[string]interface{})
+	cm.summary = ""
+	cm.summarized = 0
 }
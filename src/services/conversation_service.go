@@ -1,6 +1,7 @@
 package services
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/banking/ai-agents-banking/src/models"
@@ -10,6 +11,7 @@ type ConversationService struct {
 	mu            sync.RWMutex
 	conversations map[string]*models.Conversation
 	memories      map[string]*ConversationMemory
+	nextMessageID int64
 }
 
 func NewConversationService() *ConversationService {
@@ -23,6 +25,11 @@ func (s *ConversationService) GetOrCreateConversation(sessionID string) *models.
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.getOrCreate(sessionID)
+}
+
+// getOrCreate returns the conversation of the session, creating it if needed; callers hold s.mu
+func (s *ConversationService) getOrCreate(sessionID string) *models.Conversation {
 	conv, exists := s.conversations[sessionID]
 	if !exists {
 		conv = &models.Conversation{
@@ -38,31 +45,65 @@ func (s *ConversationService) GetOrCreateConversation(sessionID string) *models.
 }
 
 func (s *ConversationService) AddMessage(sessionID, role, content, intent string, actions []string, entities map[string]interface{}, agentName string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	conv, exists := s.conversations[sessionID]
-	if !exists {
-		conv = s.GetOrCreateConversation(sessionID)
-	}
-
-	msg := models.Message{
+	s.AppendMessage(sessionID, models.Message{
 		Role:      role,
 		Content:   content,
 		Intent:    intent,
 		Actions:   actions,
 		Entities:  entities,
 		AgentName: agentName,
-	}
+	})
+	return nil
+}
 
+// AppendMessage adds a message to the conversation and returns it with its ID set
+func (s *ConversationService) AppendMessage(sessionID string, msg models.Message) models.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv := s.getOrCreate(sessionID)
+	s.nextMessageID++
+	msg.ID = fmt.Sprintf("MSG_%d", s.nextMessageID)
 	conv.Messages = append(conv.Messages, msg)
 
 	// Update conversation memory
 	if memory, exists := s.memories[sessionID]; exists {
-		memory.AddMessage(role, content, intent, entities)
+		memory.AddMessage(msg.Role, msg.Content, msg.Intent, msg.Entities)
 	}
 
-	return nil
+	return msg
+}
+
+// TruncateConversation drops the messages from index on and returns them. The
+// conversation memory is rebuilt from the messages that remain; the rolling summary
+// survives unless it covers dropped messages.
+func (s *ConversationService) TruncateConversation(sessionID string, index int) []models.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, exists := s.conversations[sessionID]
+	if !exists || index < 0 || index >= len(conv.Messages) {
+		return nil
+	}
+
+	removed := append([]models.Message(nil), conv.Messages[index:]...)
+	conv.Messages = conv.Messages[:index:index]
+
+	if memory, exists := s.memories[sessionID]; exists {
+		summary, covered := memory.GetSummary()
+		memory.Clear()
+		if covered <= index {
+			memory.SetSummary(summary, covered)
+		}
+		for _, msg := range conv.Messages {
+			memory.AddMessage(msg.Role, msg.Content, msg.Intent, msg.Entities)
+		}
+		for key, value := range conv.Context {
+			memory.SetContext(key, value)
+		}
+	}
+
+	return removed
 }
 
 func (s *ConversationService) GetConversationHistory(sessionID string) []models.Message {
@@ -89,6 +130,38 @@ func (s *ConversationService) GetConversationMemory(sessionID string) *Conversat
 	return memory
 }
 
+// GetSummary returns the rolling summary of a conversation and how many of its
+// messages, counted from the start, it covers
+func (s *ConversationService) GetSummary(sessionID string) (string, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	memory, exists := s.memories[sessionID]
+	if !exists {
+		return "", 0
+	}
+	return memory.GetSummary()
+}
+
+// UpdateSummary stores a summary covering the messages before index to, the last of
+// which has the ID lastID. It is discarded, returning false, when the summary it was
+// built on no longer ends at from or those messages were rewound while it was written.
+func (s *ConversationService) UpdateSummary(sessionID, summary string, from, to int, lastID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, exists := s.conversations[sessionID]
+	memory, hasMemory := s.memories[sessionID]
+	if !exists || !hasMemory || to < 1 || len(conv.Messages) < to || conv.Messages[to-1].ID != lastID {
+		return false
+	}
+	if _, covered := memory.GetSummary(); covered != from {
+		return false
+	}
+	memory.SetSummary(summary, to)
+	return true
+}
+
 func (s *ConversationService) ClearConversation(sessionID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -103,11 +176,7 @@ func (s *ConversationService) UpdateContext(sessionID string, key string, value
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	conv, exists := s.conversations[sessionID]
-	if !exists {
-		conv = s.GetOrCreateConversation(sessionID)
-	}
-
+	conv := s.getOrCreate(sessionID)
 	conv.Context[key] = value
 
 	// Update memory context
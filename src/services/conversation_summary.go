@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+// summaryTimeout bounds writing a summary, which happens after the turn and nobody waits on
+const summaryTimeout = time.Minute
+
+// promptContext gathers what a prompt about message is assembled from: the rolling
+// summary, the turns of history it does not cover, the pending step and the
+// customer's accounts
+func (o *ChatOrchestrator) promptContext(session *models.UserSession, message string, history []models.Message) *models.StreamingContext {
+	summary, covered := o.conversationService.GetSummary(session.ID)
+	covered = min(covered, len(history))
+	accounts, _ := o.agentService.GetAccounts(session.Principal())
+
+	return &models.StreamingContext{
+		Message:      message,
+		Conversation: &models.Conversation{ID: session.ID, Messages: history[covered:]},
+		Summary:      summary,
+		PendingStep:  o.dialogueState.GetPendingStep(session),
+		Accounts:     accounts,
+		Session:      session,
+	}
+}
+
+// summarize folds the older turns of a conversation into its rolling summary once
+// they no longer fit the prompt budget. The model writes the summary in the
+// background, one conversation at a time, so the next turn is not held up.
+func (o *ChatOrchestrator) summarize(sessionID string) {
+	conversation := o.conversationService.GetOrCreateConversation(sessionID)
+	history := make([]models.Message, len(conversation.Messages))
+	copy(history, conversation.Messages)
+
+	earlier, covered := o.conversationService.GetSummary(sessionID)
+	cut := o.assembler.SummaryCut(history, covered)
+	if cut <= covered {
+		return
+	}
+
+	o.summaryMu.Lock()
+	if o.summarizing[sessionID] {
+		o.summaryMu.Unlock()
+		return
+	}
+	o.summarizing[sessionID] = true
+	o.summaryMu.Unlock()
+
+	go func() {
+		defer func() {
+			o.summaryMu.Lock()
+			delete(o.summarizing, sessionID)
+			o.summaryMu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+		defer cancel()
+
+		summary, err := o.llamaService.Summarize(ctx, earlier, history[covered:cut])
+		if err != nil || summary == "" {
+			log.Printf("[Context] Could not summarize %s: %v", sessionID, err)
+			return
+		}
+		if o.conversationService.UpdateSummary(sessionID, summary, covered, cut, history[cut-1].ID) {
+			log.Printf("[Context] Summary of %s now covers %d messages", sessionID, cut)
+		}
+	}()
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/banking/ai-agents-banking/src/entity"
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+// DialogueStateService tracks the in-progress ConversationStep of each session so
+// that an agent asking for missing parameters can be resumed by the next message.
+type DialogueStateService struct {
+	resetPhrases []string
+}
+
+func NewDialogueStateService() *DialogueStateService {
+	return &DialogueStateService{
+		resetPhrases: []string{"cancel", "start over", "restart", "never mind", "nevermind", "forget it", "stop", "reset"},
+	}
+}
+
+// IsReset reports whether the message asks to abandon the current flow
+func (d *DialogueStateService) IsReset(message string) bool {
+	lowerMsg := strings.Trim(strings.ToLower(strings.TrimSpace(message)), ".!")
+	for _, phrase := range d.resetPhrases {
+		if lowerMsg == phrase || strings.HasPrefix(lowerMsg, phrase+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPendingStep returns the incomplete step of the session, if any
+func (d *DialogueStateService) GetPendingStep(session *models.UserSession) *models.ConversationStep {
+	step := session.GetCurrentStep()
+	if step == nil || step.Complete {
+		return nil
+	}
+	return step
+}
+
+// Clear drops the current step of the session
+func (d *DialogueStateService) Clear(session *models.UserSession) {
+	session.SetCurrentStep(nil)
+}
+
+// Restore makes a copy of step the current step of the session; nil leaves it without one
+func (d *DialogueStateService) Restore(session *models.UserSession, step *models.ConversationStep) {
+	session.SetCurrentStep(step.Clone())
+}
+
+// Merge fills the pending step with values found in a follow-up answer.
+// Only the slots the owning agent needs are considered, so "1234567890" becomes an
+// account number in an add-payee flow and an amount nowhere else.
+// It returns the names of the parameters that were filled.
+func (d *DialogueStateService) Merge(step *models.ConversationStep, required []string, message string) []string {
+	slots := make(map[string]bool)
+	for _, param := range required {
+		slots[param] = true
+	}
+	for _, param := range step.Missing {
+		slots[normalizeSlot(param)] = true
+	}
+
+	if step.Parameters == nil {
+		step.Parameters = make(map[string]interface{})
+	}
+
+	var filled []string
+	for name, value := range extractSlotValues(message, slots, firstMissingSlot(step)) {
+		step.Parameters[name] = value
+		filled = append(filled, name)
+	}
+	return filled
+}
+
+// Track records the outcome of an agent turn: a request for more input keeps
+// (or starts) the step, anything else completes it.
+func (d *DialogueStateService) Track(session *models.UserSession, step *models.ConversationStep, intent string, params map[string]interface{}, response *models.AgentResponse) *models.ConversationStep {
+	if !response.RequiresInput {
+		if step != nil {
+			step.Complete = true
+			step.Missing = nil
+			step.Handoff = nil
+		}
+		d.Clear(session)
+		return step
+	}
+
+	if step == nil || step.AgentName != response.AgentName {
+		step = &models.ConversationStep{
+			StepID:     fmt.Sprintf("STEP_%d", time.Now().UnixNano()),
+			Intent:     intent,
+			Parameters: params,
+			AgentName:  response.AgentName,
+		}
+	}
+	step.Missing = response.MissingParameters
+	step.Complete = false
+	step.Handoff = response.Handoff
+
+	session.SetCurrentStep(step)
+	return step
+}
+
+// HandOff replaces the pending step with the one its handoff offered, owned by the
+// other agent and started with the parameters the handoff carries
+func (d *DialogueStateService) HandOff(session *models.UserSession, step *models.ConversationStep) *models.ConversationStep {
+	next := &models.ConversationStep{
+		StepID:     fmt.Sprintf("STEP_%d", time.Now().UnixNano()),
+		Intent:     step.Handoff.Intent,
+		Parameters: make(map[string]interface{}, len(step.Handoff.Parameters)),
+		AgentName:  step.Handoff.Agent,
+	}
+	for name, value := range step.Handoff.Parameters {
+		next.Parameters[name] = value
+	}
+	session.SetCurrentStep(next)
+	return next
+}
+
+// normalizeSlot maps validation markers such as "valid_amount" back to the parameter they refer to
+func normalizeSlot(param string) string {
+	switch param {
+	case "valid_amount":
+		return "amount"
+	case "valid_ifsc":
+		return "ifsc_code"
+	case "valid_account":
+		return "account_number"
+	case "valid_tenure":
+		return "tenure"
+	case "payee", "to_account":
+		return "recipient"
+	}
+	return param
+}
+
+func firstMissingSlot(step *models.ConversationStep) string {
+	if len(step.Missing) == 0 {
+		return ""
+	}
+	return normalizeSlot(step.Missing[0])
+}
+
+var (
+	slotMethodRegex   = regexp.MustCompile(`(?i)\b(upi|imps|neft|rtgs)\b`)
+	slotIFSCRegex     = regexp.MustCompile(`(?i)\b([A-Z]{4}0[A-Z0-9]{6})\b`)
+	slotAccountRegex  = regexp.MustCompile(`\b(\d{9,18})\b`)
+	slotNumberRegex   = regexp.MustCompile(`\b(\d+)\b`)
+	slotDigitsRegex   = regexp.MustCompile(`^(\d{3,18})$`)
+	slotLoanTypeRegex = regexp.MustCompile(`(?i)\b(personal|home|car|education)\b`)
+	slotNameRegex     = regexp.MustCompile(`^[A-Za-z][A-Za-z .'-]*$`)
+)
+
+// extractSlotValues pulls values for the given slots out of a free-form answer.
+// Specific formats are matched first and removed from the text so that, for
+// example, the digits of an IFSC code are never read as an amount.
+func extractSlotValues(message string, slots map[string]bool, expected string) map[string]interface{} {
+	values := make(map[string]interface{})
+	text := message
+
+	consume := func(re *regexp.Regexp) []string {
+		matches := re.FindStringSubmatch(text)
+		if len(matches) > 1 {
+			text = strings.Replace(text, matches[0], " ", 1)
+		}
+		return matches
+	}
+	consumeSpan := func(span entity.Span) {
+		text = text[:span.Start] + " " + text[span.End:]
+	}
+
+	if slots["method"] {
+		if matches := consume(slotMethodRegex); len(matches) > 1 {
+			values["method"] = strings.ToUpper(matches[1])
+		}
+	}
+	if slots["ifsc_code"] {
+		if matches := consume(slotIFSCRegex); len(matches) > 1 {
+			values["ifsc_code"] = strings.ToUpper(matches[1])
+		}
+	}
+	if slots["account_number"] {
+		if matches := consume(slotAccountRegex); len(matches) > 1 {
+			values["account_number"] = matches[1]
+		}
+	}
+	if slots["tenure"] {
+		if tenure, ok := entity.FindTenure(text); ok {
+			values["tenure"] = tenure.Count
+			values["tenure_unit"] = tenure.Unit
+			consumeSpan(tenure.Span)
+		}
+	}
+	if slots["loan_type"] {
+		if matches := consume(slotLoanTypeRegex); len(matches) > 1 {
+			values["loan_type"] = strings.ToLower(matches[1])
+		}
+	}
+	// A bare number answers a tenure question when no unit was given
+	if slots["tenure"] && expected == "tenure" && values["tenure"] == nil {
+		if matches := consume(slotNumberRegex); len(matches) > 1 {
+			values["tenure"], _ = strconv.Atoi(matches[1])
+		}
+	}
+	// Digits answering who a transfer is for are an account, not an amount
+	if slots["amount"] && expected != "recipient" {
+		if amount, ok := entity.FindAmount(text); ok {
+			values["amount"] = amount.Rupees
+			consumeSpan(amount.Span)
+		}
+	}
+
+	// Free-text slots are only taken when the answer is nothing but a name
+	if len(values) == 0 && (expected == "payee_name" || expected == "recipient") {
+		answer := strings.TrimSpace(message)
+		if slotNameRegex.MatchString(answer) {
+			values[expected] = answer
+		}
+	}
+	// A recipient may also be given by UPI ID, account number or the last digits of one
+	if len(values) == 0 && expected == "recipient" {
+		if upi, ok := entity.FindUPIID(message); ok {
+			values["recipient"] = upi.ID
+		} else if suffix, ok := entity.FindAccountSuffix(message); ok {
+			values["recipient"] = suffix.Digits
+		} else if matches := slotDigitsRegex.FindStringSubmatch(strings.TrimSpace(message)); matches != nil {
+			values["recipient"] = matches[1]
+		}
+	}
+
+	return values
+}
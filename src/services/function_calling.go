@@ -0,0 +1,207 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+var errToolsUnsupported = errors.New("model does not support native tool calling")
+
+// ChatMessage is a message of a role-structured conversation, in the Ollama /api/chat
+// format. Providers with another wire format translate it.
+type ChatMessage struct {
+	Role       string        `json:"role"`
+	Content    string        `json:"content"`
+	ToolCalls  []LLMToolCall `json:"tool_calls,omitempty"`
+	ToolName   string        `json:"tool_name,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"` // The call a tool message answers
+}
+
+// LLMToolCall is a function call requested by the model
+type LLMToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string        `json:"name"`
+		Arguments ToolArguments `json:"arguments"`
+	} `json:"function"`
+}
+
+// ToolArguments accepts arguments either as a JSON object (Ollama) or as a
+// JSON-encoded string (OpenAI-compatible servers)
+type ToolArguments map[string]interface{}
+
+func (a *ToolArguments) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err == nil {
+		data = []byte(encoded)
+	}
+	args := make(map[string]interface{})
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &args); err != nil {
+			return err
+		}
+	}
+	*a = args
+	return nil
+}
+
+// ToolDefinition advertises a registered tool to the model as a function
+type ToolDefinition struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		Parameters  ToolSchema `json:"parameters"`
+	} `json:"function"`
+}
+
+// ChatWithTools sends one non-streaming chat request offering the given tools.
+// Models that reject native tools are asked again with the tools described in the
+// system prompt, and a JSON object in the reply text is read as a tool call.
+func (s *LlamaService) ChatWithTools(ctx context.Context, messages []ChatMessage, tools []ToolDefinition) (*ChatMessage, error) {
+	if len(tools) > 0 && s.nativeToolsSupported() {
+		reply, err := s.provider.Chat(ctx, messages, tools, toolOptions)
+		if err == nil {
+			if len(reply.ToolCalls) == 0 {
+				reply.ToolCalls = parseTextToolCalls(reply.Content, tools)
+			}
+			return reply, nil
+		}
+		if !errors.Is(err, errToolsUnsupported) {
+			return nil, err
+		}
+		log.Printf("[Llama] Model %s has no native tool support, describing tools in the prompt instead", s.provider.Model())
+		s.mu.Lock()
+		s.nativeTools = false
+		s.mu.Unlock()
+	}
+
+	reply, err := s.provider.Chat(ctx, withToolInstructions(messages, tools), nil, toolOptions)
+	if err != nil {
+		return nil, err
+	}
+	reply.ToolCalls = parseTextToolCalls(reply.Content, tools)
+	return reply, nil
+}
+
+func (s *LlamaService) nativeToolsSupported() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nativeTools
+}
+
+// BuildChatMessages turns the conversation so far into chat messages for function
+// calling, within the same token budget as replies
+func (s *LlamaService) BuildChatMessages(ctx *models.StreamingContext) []ChatMessage {
+	return s.assembler.Assemble(Prompt{
+		System:      s.prompts.SystemPrompt(promptData(ctx, "")),
+		PendingStep: ctx.PendingStep,
+		Message:     ctx.Message,
+		Summary:     ctx.Summary,
+		History:     conversationMessages(ctx.Conversation),
+	})
+}
+
+// historyMessages converts conversation messages to chat messages
+func historyMessages(history []models.Message) []ChatMessage {
+	messages := make([]ChatMessage, 0, len(history))
+	for _, msg := range history {
+		role := "user"
+		if msg.Role == "assistant" {
+			role = "assistant"
+		}
+		messages = append(messages, ChatMessage{Role: role, Content: msg.Content})
+	}
+	return messages
+}
+
+// Definitions returns the registered tools as function definitions, sorted by name
+func (tr *ToolRegistry) Definitions() []ToolDefinition {
+	tools := tr.ListTools()
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name() < tools[j].Name() })
+
+	definitions := make([]ToolDefinition, len(tools))
+	for i, tool := range tools {
+		definitions[i].Type = "function"
+		definitions[i].Function.Name = tool.Name()
+		definitions[i].Function.Description = tool.Description()
+		definitions[i].Function.Parameters = tool.Parameters()
+	}
+	return definitions
+}
+
+// withToolInstructions describes the tools in the system prompt for models without native tool support
+func withToolInstructions(messages []ChatMessage, tools []ToolDefinition) []ChatMessage {
+	if len(tools) == 0 {
+		return messages
+	}
+
+	var instructions strings.Builder
+	instructions.WriteString("\n\nYou can call the following tools:\n")
+	for _, tool := range tools {
+		schema, _ := json.Marshal(tool.Function.Parameters)
+		instructions.WriteString(fmt.Sprintf("- %s: %s. Parameters: %s\n", tool.Function.Name, tool.Function.Description, schema))
+	}
+	instructions.WriteString("To call a tool, reply with only a JSON object such as {\"tool\": \"get_interest_rates\", \"arguments\": {\"product_type\": \"fd\"}}. ")
+	instructions.WriteString("Tool results are sent back to you as messages from the tool. When you have what you need, answer the customer in plain text.")
+
+	described := make([]ChatMessage, len(messages))
+	copy(described, messages)
+	if len(described) > 0 && described[0].Role == "system" {
+		described[0].Content += instructions.String()
+	} else {
+		described = append([]ChatMessage{{Role: "system", Content: strings.TrimSpace(instructions.String())}}, described...)
+	}
+	return described
+}
+
+// parseTextToolCalls reads a tool call written as JSON in the reply text, optionally
+// inside a code fence. Only the names of offered tools are accepted.
+func parseTextToolCalls(content string, tools []ToolDefinition) []LLMToolCall {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return nil
+	}
+
+	var call struct {
+		Tool       string        `json:"tool"`
+		Name       string        `json:"name"`
+		Arguments  ToolArguments `json:"arguments"`
+		Parameters ToolArguments `json:"parameters"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &call); err != nil {
+		return nil
+	}
+
+	name := call.Tool
+	if name == "" {
+		name = call.Name
+	}
+	args := call.Arguments
+	if args == nil {
+		args = call.Parameters
+	}
+	if args == nil {
+		args = make(ToolArguments)
+	}
+
+	for _, tool := range tools {
+		if tool.Function.Name == name {
+			var toolCall LLMToolCall
+			toolCall.Function.Name = name
+			toolCall.Function.Arguments = args
+			return []LLMToolCall{toolCall}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/banking/ai-agents-banking/src/entity"
+)
+
+// generalQueryIntent is recognised when no intent of the catalog matches
+const generalQueryIntent = "general_query"
+
+// defaultPatternWeight is the confidence a matching pattern adds when the catalog sets none
+const defaultPatternWeight = 0.5
+
+// Intent is an intent recognised in a message
+type Intent struct {
+	Name       string
+	Confidence float64
+	Entities   map[string]interface{}
+}
+
+// IntentDefinition is an intent of the catalog. Its confidence for a message is the
+// pattern weight for every pattern that matches plus the weight of every word of the
+// message that is one of its keywords.
+type IntentDefinition struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Agent       string             `json:"agent,omitempty"` // Agent the intent is routed to; routed by keywords alone when empty
+	Patterns    []string           `json:"patterns,omitempty"`
+	Keywords    map[string]float64 `json:"keywords,omitempty"` // Lower-case words, by weight
+	Entities    []EntityExtractor  `json:"entities,omitempty"`
+
+	patterns []*regexp.Regexp
+}
+
+// EntityExtractor finds an entity in a message recognised as its intent. When several
+// extractors name the same entity, the first that finds a value wins, and none
+// replaces a typed value the entity package found.
+type EntityExtractor struct {
+	Name    string            `json:"name"`
+	Pattern string            `json:"pattern"`
+	Group   int               `json:"group,omitempty"`  // Capture group holding the value; 1 when unset
+	Strip   string            `json:"strip,omitempty"`  // Characters removed from the value, e.g. thousands separators
+	Case    string            `json:"case,omitempty"`   // upper or lower to change the case of the value
+	Values  map[string]string `json:"values,omitempty"` // Canonical values by lower-case text found; other text is ignored
+
+	pattern *regexp.Regexp
+}
+
+// IntentCatalogFile is the layout of the catalog file
+type IntentCatalogFile struct {
+	Version       string             `json:"version"`
+	PatternWeight float64            `json:"pattern_weight,omitempty"` // 0.5 when unset
+	Intents       []IntentDefinition `json:"intents"`
+}
+
+// IntentCatalogInfo describes the loaded catalog
+type IntentCatalogInfo struct {
+	Version  string    `json:"version"`
+	Hash     string    `json:"hash"` // Of the file, so edits without a version bump show
+	Path     string    `json:"path"`
+	Intents  int       `json:"intents"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// IntentCatalog recognises intents and their entities in messages, from definitions
+// loaded from a JSON file. The file is validated whole before it is used: a reload
+// that fails keeps the catalog in use, so a broken edit never stops recognition.
+type IntentCatalog struct {
+	path   string
+	agents map[string]bool
+
+	mu      sync.RWMutex
+	catalog *IntentCatalogFile
+	info    IntentCatalogInfo
+	modTime time.Time
+}
+
+// NewIntentCatalog loads the catalog at path. Intents may only be routed to the named
+// agents.
+func NewIntentCatalog(path string, agents []string) (*IntentCatalog, error) {
+	c := &IntentCatalog{path: path, agents: make(map[string]bool)}
+	for _, agent := range agents {
+		c.agents[agent] = true
+	}
+	if _, err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads and validates the catalog file again and swaps it in
+func (c *IntentCatalog) Reload() (IntentCatalogInfo, error) {
+	stat, err := os.Stat(c.path)
+	if err != nil {
+		return IntentCatalogInfo{}, err
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return IntentCatalogInfo{}, err
+	}
+	catalog, err := c.parse(data)
+	if err != nil {
+		c.mu.Lock()
+		c.modTime = stat.ModTime() // Not retried until the file changes again
+		c.mu.Unlock()
+		return IntentCatalogInfo{}, fmt.Errorf("%s: %v", c.path, err)
+	}
+
+	sum := sha256.Sum256(data)
+	info := IntentCatalogInfo{
+		Version:  catalog.Version,
+		Hash:     hex.EncodeToString(sum[:])[:12],
+		Path:     c.path,
+		Intents:  len(catalog.Intents),
+		LoadedAt: time.Now(),
+	}
+
+	c.mu.Lock()
+	c.catalog = catalog
+	c.info = info
+	c.modTime = stat.ModTime()
+	c.mu.Unlock()
+
+	log.Printf("[Intents] Loaded %d intents from %s, version %s@%s", info.Intents, c.path, info.Version, info.Hash)
+	return info, nil
+}
+
+// StartWatching reloads the catalog whenever its file changes, checking every interval
+func (c *IntentCatalog) StartWatching(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		stat, err := os.Stat(c.path)
+		if err != nil {
+			continue
+		}
+		c.mu.RLock()
+		changed := !stat.ModTime().Equal(c.modTime)
+		c.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if _, err := c.Reload(); err != nil {
+			log.Printf("[Intents] Keeping version %s: %v", c.Info().Version, err)
+		}
+	}
+}
+
+// Info describes the catalog in use
+func (c *IntentCatalog) Info() IntentCatalogInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.info
+}
+
+// Definitions returns the intents of the catalog in use, in file order
+func (c *IntentCatalog) Definitions() []IntentDefinition {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.catalog.Intents
+}
+
+// AgentFor returns the agent the catalog routes an intent to. known is false for
+// intents the catalog does not define.
+func (c *IntentCatalog) AgentFor(intent string) (agent string, known bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, definition := range c.catalog.Intents {
+		if definition.Name == intent {
+			return definition.Agent, true
+		}
+	}
+	return "", false
+}
+
+// RecognizeIntent returns the intent with the highest confidence for the message and
+// the entities found for it. Ties go to the intent defined first; a message nothing
+// matches is a general query. Amounts, tenures, dates, account suffixes and UPI IDs
+// are read by the entity package as typed values; the extractors of the intent add
+// the entities it does not know, such as names.
+func (c *IntentCatalog) RecognizeIntent(message string) *Intent {
+	c.mu.RLock()
+	catalog := c.catalog
+	c.mu.RUnlock()
+
+	words := strings.Fields(strings.ToLower(message))
+	var best *IntentDefinition
+	highestConfidence := 0.0
+	for i := range catalog.Intents {
+		definition := &catalog.Intents[i]
+		confidence := 0.0
+		for _, pattern := range definition.patterns {
+			if pattern.MatchString(message) {
+				confidence += catalog.PatternWeight
+			}
+		}
+		for _, word := range words {
+			confidence += definition.Keywords[word]
+		}
+		if confidence > highestConfidence {
+			highestConfidence = confidence
+			best = definition
+		}
+	}
+
+	entities := entity.Extract(message, time.Now())
+	if best == nil {
+		return &Intent{
+			Name:       generalQueryIntent,
+			Confidence: 0.0,
+			Entities:   entities,
+		}
+	}
+	best.extractEntities(message, entities)
+	return &Intent{
+		Name:       best.Name,
+		Confidence: highestConfidence,
+		Entities:   entities,
+	}
+}
+
+// extractEntities adds the entities the intent's extractors find and entities lacks
+func (d *IntentDefinition) extractEntities(message string, entities map[string]interface{}) {
+	for i := range d.Entities {
+		extractor := &d.Entities[i]
+		if _, found := entities[extractor.Name]; found {
+			continue
+		}
+		if value, ok := extractor.extract(message); ok {
+			entities[extractor.Name] = value
+		}
+	}
+}
+
+func (e *EntityExtractor) extract(message string) (string, bool) {
+	matches := e.pattern.FindStringSubmatch(message)
+	if matches == nil {
+		return "", false
+	}
+	value := matches[e.group()]
+	for _, r := range e.Strip {
+		value = strings.ReplaceAll(value, string(r), "")
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", false
+	}
+
+	if e.Values != nil {
+		canonical, listed := e.Values[strings.ToLower(value)]
+		return canonical, listed
+	}
+	switch e.Case {
+	case "upper":
+		value = strings.ToUpper(value)
+	case "lower":
+		value = strings.ToLower(value)
+	}
+	return value, true
+}
+
+func (e *EntityExtractor) group() int {
+	if e.Group == 0 {
+		return 1
+	}
+	return e.Group
+}
+
+// parse decodes and validates a catalog file, compiling its patterns
+func (c *IntentCatalog) parse(data []byte) (*IntentCatalogFile, error) {
+	var catalog IntentCatalogFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&catalog); err != nil {
+		return nil, err
+	}
+	if len(catalog.Intents) == 0 {
+		return nil, fmt.Errorf("no intents")
+	}
+	if catalog.PatternWeight < 0 {
+		return nil, fmt.Errorf("negative pattern_weight")
+	}
+	if catalog.PatternWeight == 0 {
+		catalog.PatternWeight = defaultPatternWeight
+	}
+
+	names := make(map[string]bool)
+	for i := range catalog.Intents {
+		definition := &catalog.Intents[i]
+		if err := c.compile(definition); err != nil {
+			return nil, fmt.Errorf("intent %d (%s): %v", i+1, definition.Name, err)
+		}
+		if names[definition.Name] {
+			return nil, fmt.Errorf("intent %s is defined twice", definition.Name)
+		}
+		names[definition.Name] = true
+	}
+	return &catalog, nil
+}
+
+func (c *IntentCatalog) compile(definition *IntentDefinition) error {
+	switch {
+	case definition.Name == "":
+		return fmt.Errorf("no name")
+	case definition.Name == generalQueryIntent:
+		return fmt.Errorf("%s is recognised when nothing else is and cannot be defined", generalQueryIntent)
+	case len(definition.Patterns) == 0 && len(definition.Keywords) == 0:
+		return fmt.Errorf("no patterns or keywords, so it can never be recognised")
+	case definition.Agent != "" && len(c.agents) > 0 && !c.agents[definition.Agent]:
+		return fmt.Errorf("unknown agent %s (agents: %s)", definition.Agent, strings.Join(sortedKeys(c.agents), ", "))
+	}
+
+	definition.patterns = make([]*regexp.Regexp, len(definition.Patterns))
+	for i, pattern := range definition.Patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %d: %v", i+1, err)
+		}
+		definition.patterns[i] = compiled
+	}
+
+	for keyword, weight := range definition.Keywords {
+		if keyword != strings.ToLower(keyword) || len(strings.Fields(keyword)) != 1 {
+			return fmt.Errorf("keyword %q must be one lower-case word", keyword)
+		}
+		if weight <= 0 {
+			return fmt.Errorf("keyword %q needs a positive weight", keyword)
+		}
+	}
+
+	for i := range definition.Entities {
+		extractor := &definition.Entities[i]
+		if extractor.Name == "" {
+			return fmt.Errorf("entity %d has no name", i+1)
+		}
+		compiled, err := regexp.Compile(extractor.Pattern)
+		if err != nil {
+			return fmt.Errorf("entity %s: %v", extractor.Name, err)
+		}
+		if extractor.Group < 0 || extractor.group() > compiled.NumSubexp() {
+			return fmt.Errorf("entity %s: pattern has no group %d", extractor.Name, extractor.group())
+		}
+		if extractor.Case != "" && extractor.Case != "upper" && extractor.Case != "lower" {
+			return fmt.Errorf("entity %s: case must be upper or lower", extractor.Name)
+		}
+		for text := range extractor.Values {
+			if text != strings.ToLower(text) {
+				return fmt.Errorf("entity %s: value %q must be lower-case to be found", extractor.Name, text)
+			}
+		}
+		extractor.pattern = compiled
+	}
+	return nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
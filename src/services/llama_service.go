@@ -1,72 +1,45 @@
 package services
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/banking/ai-agents-banking/src/models"
 )
 
+// LlamaService phrases replies and picks tools through the configured LLMProvider
 type LlamaService struct {
-	baseURL    string
-	httpClient *http.Client
-	model      string
-	apiKey     string
-	apiURL     string
+	provider        LLMProvider
+	prompts         *PromptStore
+	assembler       *PromptAssembler
+	tokensPerSecond float64 // Default smoothing of streamed replies; 0 passes model tokens through
+	mu              sync.RWMutex
+	nativeTools     bool // Cleared once the model rejects the tools field
 }
 
-type LlamaRequest struct {
-	Model   string                 `json:"model"`
-	Prompt  string                 `json:"prompt"`
-	Stream  bool                   `json:"stream"`
-	Options map[string]interface{} `json:"options,omitempty"`
-}
-
-type LlamaResponse struct {
-	Model              string `json:"model"`
-	CreatedAt          string `json:"created_at"`
-	Response           string `json:"response"`
-	Done               bool   `json:"done"`
-	DoneReason         string `json:"done_reason,omitempty"`
-	Context            []int  `json:"context,omitempty"`
-	TotalDuration      int64  `json:"total_duration,omitempty"`
-	LoadDuration       int64  `json:"load_duration,omitempty"`
-	PromptEvalCount    int    `json:"prompt_eval_count,omitempty"`
-	PromptEvalDuration int64  `json:"prompt_eval_duration,omitempty"`
-	EvalCount          int    `json:"eval_count,omitempty"`
-	EvalDuration       int64  `json:"eval_duration,omitempty"`
-}
-
-func NewLlamaService(baseURL string) *LlamaService {
+func NewLlamaService(provider LLMProvider, prompts *PromptStore, assembler *PromptAssembler, tokensPerSecond float64) *LlamaService {
 	return &LlamaService{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 5 * time.Minute, // Increase timeout for longer responses
-		},
-		model:  "llama3", // Updated to use llama3
-		apiURL: baseURL,
+		provider:        provider,
+		prompts:         prompts,
+		assembler:       assembler,
+		tokensPerSecond: tokensPerSecond,
+		nativeTools:     true,
 	}
 }
 
-func (s *LlamaService) GenerateResponse(message string, agent *models.AgentResponse) (string, error) {
+func (s *LlamaService) GenerateResponse(ctx context.Context, message string, agent *models.AgentResponse) (string, error) {
 	// Create a new streaming session
-	session := &models.StreamingSession{
-		ID:        fmt.Sprintf("session_%d", time.Now().UnixNano()),
-		CreatedAt: time.Now(),
-		Content:   "",
-		Done:      false,
-	}
+	session := models.NewStreamingSession("")
+	defer session.MarkDone()
 
-	// Build the prompt
-	prompt := s.BuildPromptWithContext(&models.StreamingContext{
+	// Build the conversation
+	messages := s.BuildReplyMessages(&models.StreamingContext{
 		Message: message,
 		Intent: &models.Intent{
 			Name:       agent.AgentName,
@@ -75,163 +48,62 @@ func (s *LlamaService) GenerateResponse(message string, agent *models.AgentRespo
 		},
 	})
 
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	// Bound the generation even if the caller's context has no deadline
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
-	// Start streaming
-	s.QueryStreamingWithContext(ctx, prompt, session)
-
-	// Wait for completion or timeout
-	for !session.Done {
-		select {
-		case <-ctx.Done():
-			if ctx.Err() == context.DeadlineExceeded {
-				return "", fmt.Errorf("request timed out after 2 minutes")
-			}
-			return "", ctx.Err()
-		default:
-			time.Sleep(100 * time.Millisecond)
+	if err := s.StreamReply(ctx, messages, session); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return "", fmt.Errorf("request timed out after 2 minutes")
 		}
+		return "", err
 	}
 
 	// Return the final content
-	return session.Content, nil
+	return session.GetContent(), nil
 }
 
-func (s *LlamaService) QueryStreamingWithContext(ctx context.Context, prompt string, session *models.StreamingSession) {
-	log.Printf("Starting Llama streaming request with prompt length: %d", len(prompt))
-
-	// Prepare the request with banking-specific options
-	requestBody := LlamaRequest{
-		Model:  s.model,
-		Prompt: prompt,
-		Stream: true,
-		Options: map[string]interface{}{
-			"temperature":    0.7,                         // Balanced creativity
-			"top_p":          0.9,                         // Good diversity
-			"top_k":          40,                          // Reasonable token selection
-			"max_tokens":     1000,                        // Reasonable response length
-			"stop":           []string{"Human:", "User:"}, // Stop tokens
-			"repeat_penalty": 1.1,                         // Avoid repetition
-		},
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		log.Printf("Error marshaling request body: %v", err)
-		session.AppendContent(fmt.Sprintf("Error: Failed to prepare request - %v", err))
-		session.MarkDone()
-		return
-	}
-
-	log.Printf("Request body: %s", string(jsonBody))
-
-	// Create the request with context
-	req, err := http.NewRequestWithContext(ctx, "POST", s.apiURL, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		session.AppendContent(fmt.Sprintf("Error: Failed to create request - %v", err))
-		session.MarkDone()
-		return
+// StreamReply streams the model's reply to messages into the session. Tokens are
+// passed through as the model produces them unless the session or the service asks
+// for smoothing. A failure is recorded as an error event, never as reply text; when
+// no text arrived at all the error is returned too, so the caller can answer another
+// way. The caller marks the session done.
+func (s *LlamaService) StreamReply(ctx context.Context, messages []ChatMessage, session *models.StreamingSession) error {
+	log.Printf("Starting %s streaming request for %s with %d messages", s.provider.Name(), s.provider.Model(), len(messages))
+
+	tokensPerSecond := session.Pacing()
+	if tokensPerSecond == 0 {
+		tokensPerSecond = s.tokensPerSecond
 	}
+	pacer := newTokenPacer(session, tokensPerSecond)
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	if s.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+s.apiKey)
-	}
-
-	log.Printf("Sending request to: %s", s.apiURL)
-
-	// Send the request
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		log.Printf("Error sending request: %v", err)
-		if strings.Contains(err.Error(), "connection refused") {
-			session.AppendContent("Error: Cannot connect to Llama service. Please ensure the Llama server is running.")
-		} else {
-			session.AppendContent(fmt.Sprintf("Error: Network error - %v", err))
-		}
-		session.MarkDone()
-		return
+	streamed := false
+	err := s.provider.StreamChat(ctx, messages, replyOptions, func(text string) {
+		streamed = true
+		pacer.Write(ctx, text)
+	})
+	pacer.Flush()
+	if err == nil {
+		return nil
 	}
-	defer resp.Body.Close()
-
-	log.Printf("Response status: %s", resp.Status)
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Error from Llama API: %s - %s", resp.Status, string(body))
 
-		if resp.StatusCode == 404 {
-			session.AppendContent("Error: Llama model not found. Please ensure the model is downloaded and available.")
-		} else if resp.StatusCode == 500 {
-			session.AppendContent("Error: Llama server error. Please check the server logs.")
-		} else {
-			session.AppendContent(fmt.Sprintf("Error: API returned status %s", resp.Status))
-		}
-		session.MarkDone()
-		return
+	if ctx.Err() != nil {
+		// The caller went away; there is nobody left to show an error to
+		log.Printf("LLM request cancelled: %v", ctx.Err())
+		return ctx.Err()
 	}
 
-	// Process the streaming response
-	s.processStreamingResponse(ctx, session, resp)
-}
-
-func (s *LlamaService) processStreamingResponse(ctx context.Context, session *models.StreamingSession, resp *http.Response) error {
-	defer resp.Body.Close()
-
-	reader := bufio.NewReader(resp.Body)
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err == io.EOF {
-					return nil
-				}
-				return fmt.Errorf("error reading response: %v", err)
-			}
-
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-
-			// Parse the JSON response
-			var llamaResp struct {
-				Response string `json:"response"`
-				Done     bool   `json:"done"`
-			}
-
-			if err := json.Unmarshal([]byte(line), &llamaResp); err != nil {
-				log.Printf("Error parsing Llama response: %v", err)
-				continue
-			}
-
-			// If we have a response, append it to the session content
-			if llamaResp.Response != "" {
-				// Split the response into words for more granular streaming
-				words := strings.Fields(llamaResp.Response)
-				for _, word := range words {
-					// Add each word with a small delay
-					session.AppendContent(word + " ")
-					time.Sleep(50 * time.Millisecond) // Small delay between words
-				}
-			}
-
-			// If the response is done, mark the session as complete
-			if llamaResp.Done {
-				session.MarkDone()
-				return nil
-			}
-		}
+	kind := ClassifyLLMError(err)
+	event := map[string]interface{}{"error": kind.Describe(), "code": kind}
+	if streamed {
+		log.Printf("LLM stream ended early: %v", err)
+		event["partial"] = true
+		err = nil
+	} else {
+		log.Printf("Error from LLM provider %s: %v", s.provider.Name(), err)
 	}
+	session.RecordEvent(models.StreamEventError, event)
+	return err
 }
 
 func (s *LlamaService) cleanContent(content string) string {
@@ -249,10 +121,8 @@ func (s *LlamaService) cleanContent(content string) string {
 	return cleaned.String()
 }
 
-// BuildPromptWithContext builds a prompt with the given context
-func (s *LlamaService) BuildPromptWithContext(ctx *models.StreamingContext) string {
-	var promptBuilder strings.Builder
-	const bankingSystemPrompt = `You are a secure and intelligent AI banking assistant integrated into a digital banking system.
+// bankingSystemPrompt is the system prompt used when no template can be rendered
+const bankingSystemPrompt = `You are a secure and intelligent AI banking assistant integrated into a digital banking system.
 
 	Your role is to help users perform a wide range of banking tasks safely, efficiently, and clearly. Always ensure user intent is well-understood, confirm sensitive operations, and provide helpful, accurate guidance at every step.
 	
@@ -275,50 +145,106 @@ func (s *LlamaService) BuildPromptWithContext(ctx *models.StreamingContext) stri
 	- If a user seems unsure, guide them step-by-step.
 	
 	You are here to make banking simpler, safer, and smarter for the user.`
-	// Add system context
-	//promptBuilder.WriteString("You are a helpful AI banking assistant. ")
-	//promptBuilder.WriteString("You help customers with banking operations like transfers, balance checks, adding payees, loans, and general banking questions. ")
-	//promptBuilder.WriteString("Be concise, helpful, and professional in your responses.\n\n")
-	promptBuilder.WriteString(bankingSystemPrompt)
 
-	// Add conversation history if available
-	if ctx.Conversation != nil && len(ctx.Conversation.Messages) > 0 {
-		promptBuilder.WriteString("Conversation history:\n")
-		// Include last 3 exchanges for context
-		start := len(ctx.Conversation.Messages) - 6
-		if start < 0 {
-			start = 0
-		}
-
-		for i := start; i < len(ctx.Conversation.Messages); i++ {
-			msg := ctx.Conversation.Messages[i]
-			role := "Human"
-			if msg.Role == "assistant" {
-				role = "Assistant"
+// BuildReplyMessages builds the conversation the customer-facing reply is generated
+// from: the system prompt rendered from the templates for the intent and agent, the
+// verified agent result, the rolling summary and as much recent history as the token
+// budget allows, then the customer's message
+func (s *LlamaService) BuildReplyMessages(ctx *models.StreamingContext) []ChatMessage {
+	// Ground the answer in what the agent and its tools actually did
+	var grounding strings.Builder
+	if ctx.AgentResponse != nil {
+		grounding.WriteString(fmt.Sprintf("Verified result from %s:\n%s\n", ctx.AgentResponse.AgentName, ctx.AgentResponse.Message))
+		if ctx.AgentResponse.Data != nil {
+			if data, err := json.Marshal(ctx.AgentResponse.Data); err == nil {
+				grounding.WriteString(fmt.Sprintf("Data: %s\n", string(data)))
 			}
-			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", role, msg.Content))
 		}
-		promptBuilder.WriteString("\n")
+		grounding.WriteString("Explain this result to the customer. Only use the facts above; do not invent amounts, accounts, references or outcomes.")
 	}
+	toolResults := truncateToTokens(grounding.String(), s.assembler.ToolResultsBudget())
 
-	// Add intent information
-	if ctx.Intent != nil && ctx.Intent.Name != "general" {
-		promptBuilder.WriteString(fmt.Sprintf("Intent: %s\n", ctx.Intent.Name))
-		if len(ctx.Intent.Entities) > 0 {
-			promptBuilder.WriteString("Entities: ")
-			for key, value := range ctx.Intent.Entities {
-				promptBuilder.WriteString(fmt.Sprintf("%s=%v ", key, value))
-			}
-			promptBuilder.WriteString("\n")
-		}
-		promptBuilder.WriteString("\n")
+	system := s.prompts.SystemPrompt(promptData(ctx, toolResults))
+	if toolResults != "" && strings.Contains(system, toolResults) {
+		toolResults = "" // The template placed the results itself
 	}
 
-	// Add current message
-	promptBuilder.WriteString(fmt.Sprintf("Human: %s\n", ctx.Message))
-	promptBuilder.WriteString("Assistant:")
+	return s.assembler.Assemble(Prompt{
+		System:      system,
+		PendingStep: ctx.PendingStep,
+		ToolResults: toolResults,
+		Message:     ctx.Message,
+		Summary:     ctx.Summary,
+		History:     conversationMessages(ctx.Conversation),
+	})
+}
+
+// PromptVersion identifies the prompt templates replies are phrased with
+func (s *LlamaService) PromptVersion() string {
+	return s.prompts.Info().Label()
+}
 
-	return promptBuilder.String()
+// promptData collects the template variables of a prompt
+func promptData(ctx *models.StreamingContext, toolResults string) PromptData {
+	data := PromptData{
+		Accounts:    ctx.Accounts,
+		Agent:       ctx.AgentName,
+		PendingStep: ctx.PendingStep,
+		ToolResults: toolResults,
+		Now:         time.Now(),
+	}
+	if ctx.Session != nil {
+		data.UserID = ctx.Session.Principal().UserID
+	}
+	if ctx.Intent != nil {
+		data.Intent = ctx.Intent.Name
+		data.Entities = ctx.Intent.Entities
+	}
+	if ctx.AgentResponse != nil {
+		data.Agent = ctx.AgentResponse.AgentName
+	}
+	return data
+}
+
+// conversationMessages returns the messages of a conversation that may be nil
+func conversationMessages(conversation *models.Conversation) []models.Message {
+	if conversation == nil {
+		return nil
+	}
+	return conversation.Messages
+}
+
+const summaryPrompt = `You keep a running summary of a conversation between a bank customer and a banking assistant.
+Merge the earlier summary with the new messages into one summary of at most 120 words.
+Keep what the conversation may rely on later: names, payees, accounts, amounts, dates, references, decisions made and requests still open.
+Reply with the summary only.`
+
+// summaryOptions keep summaries short and factual
+var summaryOptions = GenerationOptions{Temperature: 0.2, MaxTokens: 256}
+
+// Summarize folds messages into the earlier summary of a conversation
+func (s *LlamaService) Summarize(ctx context.Context, earlier string, messages []models.Message) (string, error) {
+	var transcript strings.Builder
+	if earlier != "" {
+		transcript.WriteString("Earlier summary:\n" + earlier + "\n\n")
+	}
+	transcript.WriteString("New messages:\n")
+	for _, msg := range messages {
+		role := "Customer"
+		if msg.Role == "assistant" {
+			role = "Assistant"
+		}
+		transcript.WriteString(fmt.Sprintf("%s: %s\n", role, msg.Content))
+	}
+
+	reply, err := s.provider.Chat(ctx, []ChatMessage{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: transcript.String()},
+	}, nil, summaryOptions)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(reply.Content), nil
 }
 
 // QueryStreaming is a backward compatibility wrapper
@@ -326,6 +252,6 @@ func (s *LlamaService) QueryStreaming(message string, session *models.StreamingS
 	ctx := &models.StreamingContext{
 		Message: message,
 	}
-	prompt := s.BuildPromptWithContext(ctx)
-	s.QueryStreamingWithContext(context.Background(), prompt, session)
+	s.StreamReply(context.Background(), s.BuildReplyMessages(ctx), session)
+	session.MarkDone()
 }
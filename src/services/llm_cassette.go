@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Cassette modes accepted by NewCassetteProvider
+const (
+	CassetteRecord = "record" // Ask the model and save every answer
+	CassetteReplay = "replay" // Answer from saved cassettes only, never asking the model
+)
+
+// ErrCassetteMissing is returned in replay mode for a request that was never recorded
+var ErrCassetteMissing = errors.New("no cassette recorded for this request")
+
+// volatilePatterns match values that change from run to run without changing what is
+// asked: timestamps, generated references and session IDs. They are masked before a
+// request is keyed, so recordings keep matching when the same conversation is replayed.
+var volatilePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?`),
+	regexp.MustCompile(`\d{1,2} (Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) \d{4}(, \d{2}:\d{2})?`),
+	regexp.MustCompile(`\b[A-Z]+_?\d{16,}\b`),
+	regexp.MustCompile(`\b[0-9a-f]{32,}\b`),
+}
+
+// cassette is one recorded request and the model's answer to it
+type cassette struct {
+	Key        string        `json:"key"`
+	Kind       string        `json:"kind"` // stream or chat
+	Model      string        `json:"model"`
+	Messages   []ChatMessage `json:"messages"`
+	Tools      []string      `json:"tools,omitempty"`
+	Chunks     []string      `json:"chunks,omitempty"` // Streamed text, in the pieces it arrived in
+	Reply      *ChatMessage  `json:"reply,omitempty"`
+	RecordedAt time.Time     `json:"recorded_at"`
+}
+
+// CassetteProvider records the answers of a provider to files, one per request, and
+// replays them later without the model. Requests are keyed by their messages, tools
+// and options with volatile values masked. Failed requests are never recorded.
+type CassetteProvider struct {
+	provider LLMProvider
+	mode     string
+	dir      string
+}
+
+func NewCassetteProvider(provider LLMProvider, mode, dir string) (*CassetteProvider, error) {
+	switch mode {
+	case CassetteRecord:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	case CassetteReplay:
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q (expected %s or %s)", mode, CassetteRecord, CassetteReplay)
+	}
+	return &CassetteProvider{provider: provider, mode: mode, dir: dir}, nil
+}
+
+func (p *CassetteProvider) Name() string {
+	return p.provider.Name()
+}
+
+func (p *CassetteProvider) Model() string {
+	return p.provider.Model()
+}
+
+func (p *CassetteProvider) StreamChat(ctx context.Context, messages []ChatMessage, opts GenerationOptions, emit func(text string)) error {
+	key := p.key("stream", messages, nil, opts)
+	if p.mode == CassetteReplay {
+		recorded, err := p.load("stream", key)
+		if err != nil {
+			return err
+		}
+		for _, chunk := range recorded.Chunks {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			emit(chunk)
+		}
+		return nil
+	}
+
+	var chunks []string
+	err := p.provider.StreamChat(ctx, messages, opts, func(text string) {
+		chunks = append(chunks, text)
+		emit(text)
+	})
+	if err == nil {
+		p.save(&cassette{Key: key, Kind: "stream", Messages: messages, Chunks: chunks})
+	}
+	return err
+}
+
+func (p *CassetteProvider) Chat(ctx context.Context, messages []ChatMessage, tools []ToolDefinition, opts GenerationOptions) (*ChatMessage, error) {
+	key := p.key("chat", messages, tools, opts)
+	if p.mode == CassetteReplay {
+		recorded, err := p.load("chat", key)
+		if err != nil {
+			return nil, err
+		}
+		reply := *recorded.Reply
+		return &reply, nil
+	}
+
+	reply, err := p.provider.Chat(ctx, messages, tools, opts)
+	if err == nil {
+		p.save(&cassette{Key: key, Kind: "chat", Messages: messages, Tools: toolNames(tools), Reply: reply})
+	}
+	return reply, err
+}
+
+// key identifies a request across runs
+func (p *CassetteProvider) key(kind string, messages []ChatMessage, tools []ToolDefinition, opts GenerationOptions) string {
+	masked := make([]ChatMessage, len(messages))
+	for i, msg := range messages {
+		masked[i] = msg
+		for _, pattern := range volatilePatterns {
+			masked[i].Content = pattern.ReplaceAllString(masked[i].Content, "<volatile>")
+		}
+	}
+
+	data, _ := json.Marshal(struct {
+		Kind     string
+		Model    string
+		Messages []ChatMessage
+		Tools    []string
+		Options  GenerationOptions
+	}{kind, p.provider.Model(), masked, toolNames(tools), opts})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (p *CassetteProvider) path(kind, key string) string {
+	return filepath.Join(p.dir, fmt.Sprintf("%s-%s.json", kind, key[:20]))
+}
+
+func (p *CassetteProvider) load(kind, key string) (*cassette, error) {
+	path := p.path(kind, key)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrCassetteMissing, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var recorded cassette
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if kind == "chat" && recorded.Reply == nil {
+		return nil, fmt.Errorf("%s has no reply", path)
+	}
+	return &recorded, nil
+}
+
+// save writes a cassette through a temporary file, so a replay never reads half of one
+func (p *CassetteProvider) save(recorded *cassette) {
+	recorded.Model = p.provider.Model()
+	recorded.RecordedAt = time.Now()
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		log.Printf("[Cassette] Cannot encode %s: %v", recorded.Key, err)
+		return
+	}
+
+	path := p.path(recorded.Kind, recorded.Key)
+	tmp, err := os.CreateTemp(p.dir, ".cassette-*")
+	if err == nil {
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		log.Printf("[Cassette] Cannot record %s: %v", path, err)
+		return
+	}
+	log.Printf("[Cassette] Recorded %s", path)
+}
+
+func toolNames(tools []ToolDefinition) []string {
+	if len(tools) == 0 {
+		return nil
+	}
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Function.Name
+	}
+	return names
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/banking/ai-agents-banking/src/fakeollama"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	quietLog(t)
+	for _, kind := range []string{ProviderOllamaGenerate, ProviderOllamaChat} {
+		t.Run(kind, func(t *testing.T) {
+			fake, err := fakeollama.New(fakeollama.DefaultScript())
+			if err != nil {
+				t.Fatal(err)
+			}
+			url, err := fake.Listen("127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			provider, err := NewLLMProvider(kind, url, "llama3.2", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			ctx := context.Background()
+			// The timestamp differs between recording and replay and must not change the key
+			messages := func(now time.Time) []ChatMessage {
+				return []ChatMessage{
+					{Role: "system", Content: "You are a banking assistant. The time is " + now.Format(time.RFC3339) + "."},
+					{Role: "user", Content: "What is my balance?"},
+				}
+			}
+			recordedAt := time.Date(2026, time.October, 14, 10, 30, 0, 0, time.UTC)
+
+			recorder, err := NewCassetteProvider(provider, CassetteRecord, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var streamed strings.Builder
+			if err := recorder.StreamChat(ctx, messages(recordedAt), GenerationOptions{}, func(text string) { streamed.WriteString(text) }); err != nil {
+				t.Fatalf("recording StreamChat: %v", err)
+			}
+			reply, err := recorder.Chat(ctx, messages(recordedAt), nil, GenerationOptions{})
+			if err != nil {
+				t.Fatalf("recording Chat: %v", err)
+			}
+			if streamed.Len() == 0 || reply.Content == "" {
+				t.Fatalf("recorded nothing: streamed %q, reply %q", streamed.String(), reply.Content)
+			}
+			fake.Close()
+
+			player, err := NewCassetteProvider(provider, CassetteReplay, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			replayAt := recordedAt.Add(26 * time.Hour)
+			var replayed strings.Builder
+			if err := player.StreamChat(ctx, messages(replayAt), GenerationOptions{}, func(text string) { replayed.WriteString(text) }); err != nil {
+				t.Fatalf("replaying StreamChat: %v", err)
+			}
+			if replayed.String() != streamed.String() {
+				t.Errorf("replayed stream %q; recorded %q", replayed.String(), streamed.String())
+			}
+			replayedReply, err := player.Chat(ctx, messages(replayAt), nil, GenerationOptions{})
+			if err != nil {
+				t.Fatalf("replaying Chat: %v", err)
+			}
+			if replayedReply.Content != reply.Content {
+				t.Errorf("replayed reply %q; recorded %q", replayedReply.Content, reply.Content)
+			}
+
+			other := []ChatMessage{{Role: "user", Content: "Send 500 to Ravi"}}
+			if err := player.StreamChat(ctx, other, GenerationOptions{}, func(string) {}); !errors.Is(err, ErrCassetteMissing) {
+				t.Errorf("StreamChat() for an unrecorded request = %v; want ErrCassetteMissing", err)
+			}
+			if _, err := player.Chat(ctx, messages(replayAt), nil, GenerationOptions{Temperature: 0.9}); !errors.Is(err, ErrCassetteMissing) {
+				t.Errorf("Chat() with other options = %v; want ErrCassetteMissing", err)
+			}
+		})
+	}
+}
+
+func TestCassetteRejectsUnknownMode(t *testing.T) {
+	if _, err := NewCassetteProvider(nil, "rewind", t.TempDir()); err == nil {
+		t.Error("NewCassetteProvider() accepted an unknown mode")
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LLM provider names accepted by NewLLMProvider
+const (
+	ProviderOllamaGenerate = "ollama"
+	ProviderOllamaChat     = "ollama-chat"
+	ProviderOpenAI         = "openai"
+)
+
+// LLMProvider is an inference server able to answer a conversation. Every provider
+// takes role-structured messages; those that only accept a prompt flatten them.
+type LLMProvider interface {
+	// Name identifies the provider kind in logs
+	Name() string
+	// Model names the model the provider asks for
+	Model() string
+	// StreamChat generates a reply to messages and hands the text to emit as it arrives
+	StreamChat(ctx context.Context, messages []ChatMessage, opts GenerationOptions, emit func(text string)) error
+	// Chat generates a complete reply, offering the model the given tools. It returns
+	// errToolsUnsupported when the model or server rejects tools.
+	Chat(ctx context.Context, messages []ChatMessage, tools []ToolDefinition, opts GenerationOptions) (*ChatMessage, error)
+}
+
+// GenerationOptions are the sampling settings of a request. Zero values are left to the server.
+type GenerationOptions struct {
+	Temperature   float64
+	TopP          float64
+	TopK          int
+	MaxTokens     int
+	Stop          []string
+	RepeatPenalty float64
+}
+
+var (
+	// replyOptions phrase customer-facing replies
+	replyOptions = GenerationOptions{
+		Temperature:   0.7,                         // Balanced creativity
+		TopP:          0.9,                         // Good diversity
+		TopK:          40,                          // Reasonable token selection
+		MaxTokens:     1000,                        // Reasonable response length
+		Stop:          []string{"Human:", "User:"}, // Stop tokens
+		RepeatPenalty: 1.1,                         // Avoid repetition
+	}
+	// toolOptions pick tools, which should be predictable
+	toolOptions = GenerationOptions{Temperature: 0.2}
+)
+
+// LLMStatusError reports a response from the inference server that was not a success
+type LLMStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *LLMStatusError) Error() string {
+	return fmt.Sprintf("LLM API returned status %s: %s", e.Status, e.Body)
+}
+
+// NewLLMProvider creates the provider of the given kind. The URL may be the server
+// root or the full endpoint of the provider.
+func NewLLMProvider(kind, url, model, apiKey string) (LLMProvider, error) {
+	client := &http.Client{
+		Timeout: 5 * time.Minute, // Long replies stream for a while
+	}
+
+	switch kind {
+	case ProviderOllamaGenerate, "":
+		return &OllamaGenerateProvider{
+			url:    endpointURL(url, "/api/generate"),
+			model:  model,
+			apiKey: apiKey,
+			client: client,
+		}, nil
+	case ProviderOllamaChat:
+		return &OllamaChatProvider{
+			url:    endpointURL(url, "/api/chat"),
+			model:  model,
+			apiKey: apiKey,
+			client: client,
+		}, nil
+	case ProviderOpenAI:
+		return &OpenAIProvider{
+			url:    endpointURL(url, "/v1/chat/completions"),
+			model:  model,
+			apiKey: apiKey,
+			client: client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q (expected %s, %s or %s)", kind, ProviderOllamaGenerate, ProviderOllamaChat, ProviderOpenAI)
+	}
+}
+
+// endpointURL points a server URL at the given endpoint. URLs that already name an
+// Ollama or OpenAI-compatible endpoint are rebased, so LLAMA_URL values like
+// http://localhost:11434/api/generate keep working for every provider.
+func endpointURL(url, endpoint string) string {
+	url = strings.TrimSuffix(url, "/")
+	for _, known := range []string{"/api/generate", "/api/chat", "/v1/chat/completions", "/chat/completions", "/v1"} {
+		if strings.HasSuffix(url, known) {
+			url = strings.TrimSuffix(url, known)
+			break
+		}
+	}
+	return url + endpoint
+}
+
+// postJSON sends body to url and returns the response when its status is 200 OK
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, body interface{}) (*http.Response, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, &LLMStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(data))}
+	}
+	return resp, nil
+}
+
+// rejectsTools reports whether a failed request was refused because it offered tools.
+// Ollama answers 400 "does not support tools"; llama.cpp and vLLM refuse with 400 or
+// 500 when tool calling is not enabled on the server.
+func rejectsTools(err error) bool {
+	var statusErr *LLMStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError:
+		return strings.Contains(strings.ToLower(statusErr.Body), "tool")
+	}
+	return false
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// LLMErrorKind classifies why a request to the inference server failed
+type LLMErrorKind string
+
+const (
+	LLMErrorConnect       LLMErrorKind = "connect"         // The server could not be reached
+	LLMErrorTimeout       LLMErrorKind = "timeout"         // The server did not answer in time
+	LLMErrorModelNotFound LLMErrorKind = "model_not_found" // The server does not have the model
+	LLMErrorServer        LLMErrorKind = "server_error"    // The server failed or is overloaded
+	LLMErrorRejected      LLMErrorKind = "rejected"        // The server refused the request itself
+	LLMErrorUnavailable   LLMErrorKind = "unavailable"     // The circuit breaker is open, nothing was sent
+	LLMErrorOther         LLMErrorKind = "error"
+)
+
+// retryable reports whether a request that failed this way may succeed when sent again
+func (k LLMErrorKind) retryable() bool {
+	return k == LLMErrorConnect || k == LLMErrorTimeout || k == LLMErrorServer
+}
+
+// Describe explains the failure to the customer
+func (k LLMErrorKind) Describe() string {
+	switch k {
+	case LLMErrorConnect:
+		return "The assistant's language model cannot be reached right now."
+	case LLMErrorTimeout:
+		return "The assistant's language model did not answer in time."
+	case LLMErrorModelNotFound:
+		return "The assistant's language model is not installed on the inference server."
+	case LLMErrorServer:
+		return "The assistant's language model server ran into a problem."
+	case LLMErrorUnavailable:
+		return "The assistant's language model is temporarily unavailable."
+	default:
+		return "The assistant's language model could not produce a reply."
+	}
+}
+
+// LLMError is a classified failure of a request to a model
+type LLMError struct {
+	Kind  LLMErrorKind
+	Model string
+	Err   error
+}
+
+func (e *LLMError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Model, e.Kind, e.Err)
+}
+
+func (e *LLMError) Unwrap() error {
+	return e.Err
+}
+
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+// ClassifyLLMError tells what kind of failure err is
+func ClassifyLLMError(err error) LLMErrorKind {
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		return llmErr.Kind
+	}
+
+	var statusErr *LLMStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusNotFound:
+			return LLMErrorModelNotFound
+		case statusErr.StatusCode == http.StatusRequestTimeout:
+			return LLMErrorTimeout
+		case statusErr.StatusCode == http.StatusTooManyRequests, statusErr.StatusCode >= 500:
+			return LLMErrorServer
+		default:
+			return LLMErrorRejected
+		}
+	}
+
+	var netErr net.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return LLMErrorTimeout
+	case errors.As(err, &dnsErr), errors.As(err, &opErr) && opErr.Op == "dial",
+		errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return LLMErrorConnect
+	}
+
+	// Ollama reports a missing model inside a stream that already started with 200 OK
+	if message := strings.ToLower(err.Error()); strings.Contains(message, "model") && strings.Contains(message, "not found") {
+		return LLMErrorModelNotFound
+	}
+	return LLMErrorOther
+}
+
+// Circuit breaker states, as reported by LLMStatus
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half_open"
+)
+
+// circuitBreaker stops sending requests to a model after threshold requests in a row
+// failed. Once cooldown has passed a single request is let through to probe the
+// model; it closes the breaker when it succeeds and opens it again when it fails.
+type circuitBreaker struct {
+	threshold int // 0 disables the breaker
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a request may be sent now
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record counts the outcome of a request let through by allow and reports the state it left
+func (b *circuitBreaker) record(failed bool) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return circuitClosed
+	}
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		return circuitOpen
+	}
+	return circuitClosed
+}
+
+// release returns a request let through by allow whose outcome says nothing about the model
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *circuitBreaker) state() (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.threshold <= 0 || b.failures < b.threshold:
+		return circuitClosed, b.failures
+	case b.probing || !time.Now().Before(b.openUntil):
+		return circuitHalfOpen, b.failures
+	default:
+		return circuitOpen, b.failures
+	}
+}
+
+// LLMStatus describes the health of one model as seen by its circuit breaker
+type LLMStatus struct {
+	Model    string `json:"model"`
+	Fallback bool   `json:"fallback"`
+	State    string `json:"state"`
+	Failures int    `json:"consecutive_failures"`
+}
+
+// retryBackoff is the delay before the first retry; every further retry doubles it
+const retryBackoff = 250 * time.Millisecond
+
+// resilientModel is a provider for one model and the breaker guarding it
+type resilientModel struct {
+	provider LLMProvider
+	breaker  *circuitBreaker
+}
+
+// ResilientProvider wraps a provider with bounded retries, a circuit breaker and an
+// optional fallback model. Requests that fail before any reply text arrived are
+// retried with jittered exponential backoff when the failure may pass. When the
+// primary model is missing, failing or its breaker is open, the fallback model is
+// asked instead; it lives on the same server, so failures to connect are not handed
+// to it. Every failure comes back as an *LLMError.
+type ResilientProvider struct {
+	models  []resilientModel // Primary first, then the fallback if any
+	retries int
+}
+
+// NewResilientProvider wraps primary. fallback may be nil. A breaker threshold of 0
+// disables the circuit breakers.
+func NewResilientProvider(primary, fallback LLMProvider, retries int, breakerThreshold int, breakerCooldown time.Duration) *ResilientProvider {
+	p := &ResilientProvider{retries: max(retries, 0)}
+	for _, provider := range []LLMProvider{primary, fallback} {
+		if provider == nil {
+			continue
+		}
+		p.models = append(p.models, resilientModel{
+			provider: provider,
+			breaker:  &circuitBreaker{threshold: breakerThreshold, cooldown: breakerCooldown},
+		})
+	}
+	return p
+}
+
+func (p *ResilientProvider) Name() string {
+	return p.models[0].provider.Name()
+}
+
+func (p *ResilientProvider) Model() string {
+	return p.models[0].provider.Model()
+}
+
+func (p *ResilientProvider) StreamChat(ctx context.Context, messages []ChatMessage, opts GenerationOptions, emit func(text string)) error {
+	streamed := false
+	return p.do(ctx, func(provider LLMProvider) (bool, error) {
+		err := provider.StreamChat(ctx, messages, opts, func(text string) {
+			streamed = true
+			emit(text)
+		})
+		return streamed, err
+	})
+}
+
+func (p *ResilientProvider) Chat(ctx context.Context, messages []ChatMessage, tools []ToolDefinition, opts GenerationOptions) (*ChatMessage, error) {
+	var reply *ChatMessage
+	err := p.do(ctx, func(provider LLMProvider) (bool, error) {
+		var err error
+		reply, err = provider.Chat(ctx, messages, tools, opts)
+		return false, err
+	})
+	return reply, err
+}
+
+// Status describes the health of every model
+func (p *ResilientProvider) Status() []LLMStatus {
+	status := make([]LLMStatus, len(p.models))
+	for i, model := range p.models {
+		state, failures := model.breaker.state()
+		status[i] = LLMStatus{Model: model.provider.Model(), Fallback: i > 0, State: state, Failures: failures}
+	}
+	return status
+}
+
+// Healthy reports whether any model is taking requests
+func (p *ResilientProvider) Healthy() bool {
+	for _, model := range p.models {
+		if state, _ := model.breaker.state(); state != circuitOpen {
+			return true
+		}
+	}
+	return false
+}
+
+// do sends request to the primary model and, when that fails in a way another model
+// may not, to the fallback. request reports whether reply text was already handed on.
+func (p *ResilientProvider) do(ctx context.Context, request func(provider LLMProvider) (bool, error)) error {
+	var err error
+	for i, model := range p.models {
+		if i > 0 {
+			log.Printf("[LLM] Falling back to %s: %v", model.provider.Model(), err)
+		}
+
+		var started bool
+		started, err = p.attempt(ctx, model, request)
+		if err == nil || started || ctx.Err() != nil || errors.Is(err, errToolsUnsupported) {
+			return err
+		}
+		if kind := ClassifyLLMError(err); kind == LLMErrorConnect || kind == LLMErrorRejected {
+			return err
+		}
+	}
+	return err
+}
+
+// attempt sends request to one model, retrying failures that may pass
+func (p *ResilientProvider) attempt(ctx context.Context, model resilientModel, request func(provider LLMProvider) (bool, error)) (bool, error) {
+	name := model.provider.Model()
+	if !model.breaker.allow() {
+		return false, &LLMError{Kind: LLMErrorUnavailable, Model: name, Err: errCircuitOpen}
+	}
+
+	for try := 0; ; try++ {
+		started, err := request(model.provider)
+		if err == nil || errors.Is(err, errToolsUnsupported) {
+			// Refusing tools is an answer too; the model is up
+			p.record(model, false)
+			return started, err
+		}
+		if ctx.Err() != nil {
+			model.breaker.release()
+			return started, err
+		}
+
+		kind := ClassifyLLMError(err)
+		if started || !kind.retryable() || try >= p.retries {
+			p.record(model, kind != LLMErrorRejected)
+			return started, &LLMError{Kind: kind, Model: name, Err: err}
+		}
+
+		delay := retryBackoff << try
+		delay = delay/2 + rand.N(delay/2+1)
+		log.Printf("[LLM] %s %s, retrying in %v: %v", name, kind, delay.Round(time.Millisecond), err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			model.breaker.release()
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// record counts the outcome of a request and logs when the breaker of the model opens or closes
+func (p *ResilientProvider) record(model resilientModel, failed bool) {
+	before, _ := model.breaker.state()
+	after := model.breaker.record(failed)
+	if before != circuitOpen && after == circuitOpen {
+		log.Printf("[LLM] Circuit for %s opened; answering without it for %v", model.provider.Model(), model.breaker.cooldown)
+	} else if before != circuitClosed && after == circuitClosed {
+		log.Printf("[LLM] Circuit for %s closed", model.provider.Model())
+	}
+}
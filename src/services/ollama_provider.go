@@ -0,0 +1,262 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OllamaGenerateProvider streams replies from Ollama's /api/generate, which takes a
+// single prompt. Tool calls need role-structured messages, so Chat goes to /api/chat
+// of the same server.
+type OllamaGenerateProvider struct {
+	url    string
+	model  string
+	apiKey string
+	client *http.Client
+}
+
+type LlamaRequest struct {
+	Model   string                 `json:"model"`
+	Prompt  string                 `json:"prompt"`
+	Stream  bool                   `json:"stream"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+type LlamaResponse struct {
+	Model              string `json:"model"`
+	CreatedAt          string `json:"created_at"`
+	Response           string `json:"response"`
+	Done               bool   `json:"done"`
+	DoneReason         string `json:"done_reason,omitempty"`
+	Error              string `json:"error,omitempty"`
+	Context            []int  `json:"context,omitempty"`
+	TotalDuration      int64  `json:"total_duration,omitempty"`
+	LoadDuration       int64  `json:"load_duration,omitempty"`
+	PromptEvalCount    int    `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64  `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int    `json:"eval_count,omitempty"`
+	EvalDuration       int64  `json:"eval_duration,omitempty"`
+}
+
+func (p *OllamaGenerateProvider) Name() string  { return ProviderOllamaGenerate }
+func (p *OllamaGenerateProvider) Model() string { return p.model }
+
+func (p *OllamaGenerateProvider) StreamChat(ctx context.Context, messages []ChatMessage, opts GenerationOptions, emit func(string)) error {
+	resp, err := postJSON(ctx, p.client, p.url, p.apiKey, LlamaRequest{
+		Model:   p.model,
+		Prompt:  flattenPrompt(messages),
+		Stream:  true,
+		Options: ollamaOptions(opts),
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readLines(ctx, resp.Body, func(line string) (bool, error) {
+		var chunk LlamaResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return false, nil // Skip what cannot be parsed, as Ollama may add new kinds of lines
+		}
+		if chunk.Error != "" {
+			return true, errors.New(chunk.Error)
+		}
+		if chunk.Response != "" {
+			emit(chunk.Response)
+		}
+		return chunk.Done, nil
+	})
+}
+
+func (p *OllamaGenerateProvider) Chat(ctx context.Context, messages []ChatMessage, tools []ToolDefinition, opts GenerationOptions) (*ChatMessage, error) {
+	chat := &OllamaChatProvider{
+		url:    endpointURL(p.url, "/api/chat"),
+		model:  p.model,
+		apiKey: p.apiKey,
+		client: p.client,
+	}
+	return chat.Chat(ctx, messages, tools, opts)
+}
+
+// flattenPrompt writes role-structured messages as the Human/Assistant transcript
+// that /api/generate models are prompted with
+func flattenPrompt(messages []ChatMessage) string {
+	var system []string
+	var rest []ChatMessage
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+		} else {
+			rest = append(rest, msg)
+		}
+	}
+
+	var last *ChatMessage
+	if n := len(rest); n > 0 && rest[n-1].Role == "user" {
+		last, rest = &rest[n-1], rest[:n-1]
+	}
+
+	var prompt strings.Builder
+	if len(system) > 0 {
+		prompt.WriteString(strings.Join(system, "\n\n"))
+		prompt.WriteString("\n\n")
+	}
+
+	if len(rest) > 0 {
+		prompt.WriteString("Conversation history:\n")
+		for _, msg := range rest {
+			switch msg.Role {
+			case "assistant":
+				prompt.WriteString(fmt.Sprintf("Assistant: %s\n", msg.Content))
+			case "tool":
+				prompt.WriteString(fmt.Sprintf("Tool %s: %s\n", msg.ToolName, msg.Content))
+			default:
+				prompt.WriteString(fmt.Sprintf("Human: %s\n", msg.Content))
+			}
+		}
+		prompt.WriteString("\n")
+	}
+
+	if last != nil {
+		prompt.WriteString(fmt.Sprintf("Human: %s\n", last.Content))
+	}
+	prompt.WriteString("Assistant:")
+	return prompt.String()
+}
+
+// OllamaChatProvider talks to Ollama's /api/chat with role-structured messages
+type OllamaChatProvider struct {
+	url    string
+	model  string
+	apiKey string
+	client *http.Client
+}
+
+type llamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ChatMessage          `json:"messages"`
+	Tools    []ToolDefinition       `json:"tools,omitempty"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+type llamaChatResponse struct {
+	Message ChatMessage `json:"message"`
+	Done    bool        `json:"done"`
+	Error   string      `json:"error,omitempty"`
+}
+
+func (p *OllamaChatProvider) Name() string  { return ProviderOllamaChat }
+func (p *OllamaChatProvider) Model() string { return p.model }
+
+func (p *OllamaChatProvider) StreamChat(ctx context.Context, messages []ChatMessage, opts GenerationOptions, emit func(string)) error {
+	resp, err := postJSON(ctx, p.client, p.url, p.apiKey, llamaChatRequest{
+		Model:    p.model,
+		Messages: messages,
+		Stream:   true,
+		Options:  ollamaOptions(opts),
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readLines(ctx, resp.Body, func(line string) (bool, error) {
+		var chunk llamaChatResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return false, nil
+		}
+		if chunk.Error != "" {
+			return true, errors.New(chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			emit(chunk.Message.Content)
+		}
+		return chunk.Done, nil
+	})
+}
+
+func (p *OllamaChatProvider) Chat(ctx context.Context, messages []ChatMessage, tools []ToolDefinition, opts GenerationOptions) (*ChatMessage, error) {
+	resp, err := postJSON(ctx, p.client, p.url, p.apiKey, llamaChatRequest{
+		Model:    p.model,
+		Messages: messages,
+		Tools:    tools,
+		Stream:   false,
+		Options:  ollamaOptions(opts),
+	})
+	if err != nil {
+		if len(tools) > 0 && rejectsTools(err) {
+			return nil, errToolsUnsupported
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp llamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode chat response: %v", err)
+	}
+	if chatResp.Error != "" {
+		return nil, errors.New(chatResp.Error)
+	}
+	chatResp.Message.Role = "assistant"
+	return &chatResp.Message, nil
+}
+
+// ollamaOptions translates generation options to Ollama's option names
+func ollamaOptions(opts GenerationOptions) map[string]interface{} {
+	options := make(map[string]interface{})
+	if opts.Temperature != 0 {
+		options["temperature"] = opts.Temperature
+	}
+	if opts.TopP != 0 {
+		options["top_p"] = opts.TopP
+	}
+	if opts.TopK != 0 {
+		options["top_k"] = opts.TopK
+	}
+	if opts.MaxTokens != 0 {
+		options["num_predict"] = opts.MaxTokens
+	}
+	if len(opts.Stop) > 0 {
+		options["stop"] = opts.Stop
+	}
+	if opts.RepeatPenalty != 0 {
+		options["repeat_penalty"] = opts.RepeatPenalty
+	}
+	return options
+}
+
+// readLines hands each non-empty line of a streamed body to handle until it reports
+// the stream done, fails, the body ends or ctx ends
+func readLines(ctx context.Context, body io.Reader, handle func(line string) (bool, error)) error {
+	reader := bufio.NewReader(body)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line, err := reader.ReadString('\n')
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			done, handleErr := handle(trimmed)
+			if handleErr != nil || done {
+				return handleErr
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return fmt.Errorf("error reading response: %v", err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OpenAIProvider talks to an OpenAI-compatible /v1/chat/completions endpoint, as
+// served by llama.cpp, vLLM and LM Studio. Replies are streamed over SSE.
+type OpenAIProvider struct {
+	url    string
+	model  string
+	apiKey string
+	client *http.Client
+}
+
+type openAIRequest struct {
+	Model       string           `json:"model"`
+	Messages    []openAIMessage  `json:"messages"`
+	Tools       []ToolDefinition `json:"tools,omitempty"`
+	Stream      bool             `json:"stream"`
+	Temperature float64          `json:"temperature,omitempty"`
+	TopP        float64          `json:"top_p,omitempty"`
+	MaxTokens   int              `json:"max_tokens,omitempty"`
+	Stop        []string         `json:"stop,omitempty"`
+}
+
+// openAIMessage is a chat message in the OpenAI wire format, where tool arguments
+// travel as a JSON-encoded string and tool results name the call they answer
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+	Name       string           `json:"name,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      ChatMessage `json:"message"`
+		Delta        ChatMessage `json:"delta"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (p *OpenAIProvider) Name() string  { return ProviderOpenAI }
+func (p *OpenAIProvider) Model() string { return p.model }
+
+func (p *OpenAIProvider) StreamChat(ctx context.Context, messages []ChatMessage, opts GenerationOptions, emit func(string)) error {
+	resp, err := postJSON(ctx, p.client, p.url, p.apiKey, p.request(messages, nil, opts, true))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readLines(ctx, resp.Body, func(line string) (bool, error) {
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			return false, nil // Comments, event names and retry hints
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return true, nil
+		}
+
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return false, nil
+		}
+		if chunk.Error != nil {
+			return true, errors.New(chunk.Error.Message)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				emit(choice.Delta.Content)
+			}
+		}
+		return false, nil
+	})
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, messages []ChatMessage, tools []ToolDefinition, opts GenerationOptions) (*ChatMessage, error) {
+	resp, err := postJSON(ctx, p.client, p.url, p.apiKey, p.request(messages, tools, opts, false))
+	if err != nil {
+		if len(tools) > 0 && rejectsTools(err) {
+			return nil, errToolsUnsupported
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode chat response: %v", err)
+	}
+	if chatResp.Error != nil {
+		return nil, errors.New(chatResp.Error.Message)
+	}
+	if len(chatResp.Choices) == 0 {
+		return nil, errors.New("chat response has no choices")
+	}
+
+	reply := chatResp.Choices[0].Message
+	reply.Role = "assistant"
+	for i := range reply.ToolCalls {
+		if reply.ToolCalls[i].ID == "" {
+			reply.ToolCalls[i].ID = fmt.Sprintf("call_%d", i)
+		}
+	}
+	return &reply, nil
+}
+
+func (p *OpenAIProvider) request(messages []ChatMessage, tools []ToolDefinition, opts GenerationOptions, stream bool) openAIRequest {
+	wire := make([]openAIMessage, len(messages))
+	for i, msg := range messages {
+		wire[i] = openAIMessage{Role: msg.Role, Content: msg.Content, ToolCallID: msg.ToolCallID}
+		if msg.Role == "tool" {
+			wire[i].Name = msg.ToolName
+		}
+		for _, call := range msg.ToolCalls {
+			var toolCall openAIToolCall
+			toolCall.ID = call.ID
+			toolCall.Type = "function"
+			toolCall.Function.Name = call.Function.Name
+			args, _ := json.Marshal(call.Function.Arguments)
+			toolCall.Function.Arguments = string(args)
+			wire[i].ToolCalls = append(wire[i].ToolCalls, toolCall)
+		}
+	}
+
+	return openAIRequest{
+		Model:       p.model,
+		Messages:    wire,
+		Tools:       tools,
+		Stream:      stream,
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		MaxTokens:   opts.MaxTokens,
+		Stop:        opts.Stop,
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/banking/ai-agents-banking/src/models"
+	"github.com/banking/ai-agents-banking/src/utils"
+)
+
+var (
+	ErrConfirmationNotFound = errors.New("confirmation not found")
+	ErrConfirmationExpired  = errors.New("confirmation has expired")
+)
+
+// PendingActionService holds high-risk operations until the user explicitly confirms them.
+// Each confirmation ID can be used once; a session has at most one outstanding action.
+type PendingActionService struct {
+	mu        sync.Mutex
+	actions   map[string]*models.PendingAction
+	bySession map[string]string // sessionID -> confirmation ID
+	highRisk  map[string]bool
+	expiry    time.Duration
+}
+
+func NewPendingActionService(expiry time.Duration, highRiskTools ...string) *PendingActionService {
+	service := &PendingActionService{
+		actions:   make(map[string]*models.PendingAction),
+		bySession: make(map[string]string),
+		highRisk:  make(map[string]bool),
+		expiry:    expiry,
+	}
+	for _, tool := range highRiskTools {
+		service.highRisk[tool] = true
+	}
+	return service
+}
+
+// IsHighRisk reports whether a tool must not run without confirmation
+func (s *PendingActionService) IsHighRisk(toolName string) bool {
+	return s.highRisk[toolName]
+}
+
+// Create registers a new pending action, replacing any earlier one for the same session
+func (s *PendingActionService) Create(action *models.PendingAction) *models.PendingAction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	action.ID = "CNF_" + utils.GenerateSessionID()
+	action.CreatedAt = now
+	action.ExpiresAt = now.Add(s.expiry)
+
+	if previous, exists := s.bySession[action.SessionID]; exists {
+		delete(s.actions, previous)
+		log.Printf("[Confirmation] Superseded %s for session %s", previous, action.SessionID)
+	}
+	s.actions[action.ID] = action
+	s.bySession[action.SessionID] = action.ID
+
+	log.Printf("[Confirmation] Created %s (%s) for session %s", action.ID, action.ToolName, action.SessionID)
+	return action
+}
+
+// GetForSession returns the outstanding action of a session.
+// An action that has expired is dropped and reported as ErrConfirmationExpired.
+func (s *PendingActionService) GetForSession(sessionID string) (*models.PendingAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, exists := s.bySession[sessionID]
+	if !exists {
+		return nil, ErrConfirmationNotFound
+	}
+	action := s.actions[id]
+	if action.IsExpired() {
+		s.remove(action)
+		return nil, ErrConfirmationExpired
+	}
+	return action, nil
+}
+
+// Confirm consumes the action so that it can be executed exactly once
+func (s *PendingActionService) Confirm(sessionID, confirmationID string) (*models.PendingAction, error) {
+	return s.take(sessionID, confirmationID)
+}
+
+// Cancel discards the action without executing it
+func (s *PendingActionService) Cancel(sessionID, confirmationID string) (*models.PendingAction, error) {
+	return s.take(sessionID, confirmationID)
+}
+
+func (s *PendingActionService) take(sessionID, confirmationID string) (*models.PendingAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	action, exists := s.actions[confirmationID]
+	if !exists || action.SessionID != sessionID {
+		return nil, ErrConfirmationNotFound
+	}
+
+	s.remove(action)
+	if action.IsExpired() {
+		return nil, ErrConfirmationExpired
+	}
+	return action, nil
+}
+
+func (s *PendingActionService) remove(action *models.PendingAction) {
+	delete(s.actions, action.ID)
+	if s.bySession[action.SessionID] == action.ID {
+		delete(s.bySession, action.SessionID)
+	}
+}
+
+// IsConfirmation reports whether a chat message explicitly approves the pending action:
+// a confirmation phrase on its own, or "confirm" followed by the action's confirmation
+// ID. A longer message such as "confirm my balance first" approves nothing.
+func (s *PendingActionService) IsConfirmation(message, confirmationID string) bool {
+	return isReplyTo(message, confirmationID, "confirm", "confirm", "yes", "yes confirm", "yes, confirm", "proceed", "go ahead")
+}
+
+// IsDecline reports whether a chat message rejects the pending action: a decline phrase
+// on its own, or "cancel" followed by the action's confirmation ID
+func (s *PendingActionService) IsDecline(message, confirmationID string) bool {
+	return isReplyTo(message, confirmationID, "cancel", "no", "cancel", "don't", "dont", "do not proceed", "abort")
+}
+
+// isReplyTo matches a message against whole phrases, or verb followed by the
+// confirmation ID when there is one
+func isReplyTo(message, confirmationID, verb string, phrases ...string) bool {
+	lowerMsg := strings.Trim(strings.ToLower(strings.TrimSpace(message)), ".!")
+	for _, phrase := range phrases {
+		if lowerMsg == phrase {
+			return true
+		}
+	}
+	return confirmationID != "" && strings.Join(strings.Fields(lowerMsg), " ") == verb+" "+strings.ToLower(confirmationID)
+}
+
+// StartCleanupRoutine periodically drops expired actions
+func (s *PendingActionService) StartCleanupRoutine() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		for _, action := range s.actions {
+			if action.IsExpired() {
+				s.remove(action)
+				log.Printf("[Confirmation] Expired %s for session %s", action.ID, action.SessionID)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfirmationReplies(t *testing.T) {
+	service := NewPendingActionService(time.Minute, "fund_transfer")
+	const id = "CNF_0a1b2c3d"
+	tests := []struct {
+		message  string
+		id       string
+		confirms bool
+		declines bool
+	}{
+		{message: "confirm", id: id, confirms: true},
+		{message: "  Yes, confirm! ", id: id, confirms: true},
+		{message: "go ahead.", id: id, confirms: true},
+		{message: "confirm CNF_0a1b2c3d", id: id, confirms: true},
+		{message: "confirm  cnf_0a1b2c3d", id: id, confirms: true},
+		{message: "confirm CNF_ffffffff", id: id},
+		{message: "confirm CNF_0a1b2c3d", id: ""},
+		{message: "confirm my balance first", id: id},
+		{message: "confirm the amount is 500?", id: id},
+		{message: "yes please send more", id: id},
+		{message: "cancel", id: id, declines: true},
+		{message: "No.", id: id, declines: true},
+		{message: "cancel CNF_0a1b2c3d", id: id, declines: true},
+		{message: "cancel CNF_ffffffff", id: id},
+		{message: "cancel my card", id: id},
+		{message: "what is my balance", id: id},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if got := service.IsConfirmation(tt.message, tt.id); got != tt.confirms {
+				t.Errorf("IsConfirmation(%q, %q) = %v; want %v", tt.message, tt.id, got, tt.confirms)
+			}
+			if got := service.IsDecline(tt.message, tt.id); got != tt.declines {
+				t.Errorf("IsDecline(%q, %q) = %v; want %v", tt.message, tt.id, got, tt.declines)
+			}
+		})
+	}
+}
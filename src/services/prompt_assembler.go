@@ -0,0 +1,161 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+// messageTokenOverhead approximates the tokens a chat template adds around each message
+const messageTokenOverhead = 4
+
+// EstimateTokens approximates how many tokens text takes. Tokenizers of the Llama
+// and GPT families average about four characters per token on English text, and
+// rarely fewer than one token per word.
+func EstimateTokens(text string) int {
+	tokens := (utf8.RuneCountInString(text) + 3) / 4
+	if words := len(strings.Fields(text)); words > tokens {
+		tokens = words
+	}
+	return tokens
+}
+
+// estimateMessages approximates the tokens a list of conversation messages takes
+func estimateMessages(messages []models.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += EstimateTokens(msg.Content) + messageTokenOverhead
+	}
+	return total
+}
+
+// Prompt is what the messages sent to the model are assembled from, listed in the
+// order in which they claim the token budget
+type Prompt struct {
+	System      string                   // Instructions, always kept
+	PendingStep *models.ConversationStep // The operation the customer is in the middle of
+	ToolResults string                   // Verified results the reply must be grounded in
+	Message     string                   // The customer's message, always kept
+	Summary     string                   // Rolling summary of turns older than History
+	History     []models.Message         // Turns the summary does not cover, oldest first
+}
+
+// PromptAssembler fits prompts into a token budget. The system prompt and the
+// customer's message are always sent; the pending step, tool results, the summary
+// and the recent turns follow in that order while the budget lasts. Recent turns
+// are taken newest first, so the oldest are the first to go.
+type PromptAssembler struct {
+	budget int
+}
+
+func NewPromptAssembler(budget int) *PromptAssembler {
+	return &PromptAssembler{budget: budget}
+}
+
+// Assemble builds the messages for a prompt
+func (a *PromptAssembler) Assemble(prompt Prompt) []ChatMessage {
+	remaining := a.budget -
+		EstimateTokens(prompt.System) - messageTokenOverhead -
+		EstimateTokens(prompt.Message) - messageTokenOverhead
+
+	var system strings.Builder
+	system.WriteString(prompt.System)
+	add := func(section string, limit int) {
+		if section == "" || remaining <= 0 {
+			return
+		}
+		section = truncateToTokens(section, min(limit, remaining))
+		system.WriteString("\n\n")
+		system.WriteString(section)
+		remaining -= EstimateTokens(section)
+	}
+
+	add(describeStep(prompt.PendingStep), remaining)
+	add(prompt.ToolResults, a.ToolResultsBudget())
+	if prompt.Summary != "" {
+		add("Summary of the earlier conversation:\n"+prompt.Summary, a.budget/8)
+	}
+
+	start := len(prompt.History)
+	for start > 0 {
+		cost := EstimateTokens(prompt.History[start-1].Content) + messageTokenOverhead
+		if cost > remaining {
+			break
+		}
+		remaining -= cost
+		start--
+	}
+
+	messages := []ChatMessage{{Role: "system", Content: system.String()}}
+	messages = append(messages, historyMessages(prompt.History[start:])...)
+	return append(messages, ChatMessage{Role: "user", Content: prompt.Message})
+}
+
+// ToolResultsBudget is the most tokens tool results may take, so that large account
+// data does not crowd out the conversation
+func (a *PromptAssembler) ToolResultsBudget() int {
+	return a.budget / 3
+}
+
+// SummaryCut returns how many messages of the history the rolling summary should
+// cover. Once the turns after the summary take more than half the budget, the older
+// of them are folded in until they take a quarter of it; the last exchange is never
+// folded. The result equals covered when nothing needs summarizing.
+func (a *PromptAssembler) SummaryCut(history []models.Message, covered int) int {
+	if covered >= len(history) || estimateMessages(history[covered:]) <= a.budget/2 {
+		return covered
+	}
+
+	cut := covered
+	for cut < len(history)-2 && estimateMessages(history[cut:]) > a.budget/4 {
+		cut++
+	}
+	return cut
+}
+
+// describeStep tells the model which operation the customer is in the middle of
+func describeStep(step *models.ConversationStep) string {
+	if step == nil || step.Complete {
+		return ""
+	}
+
+	var description strings.Builder
+	description.WriteString(fmt.Sprintf("The customer is in the middle of %s.", strings.ReplaceAll(step.Intent, "_", " ")))
+	if len(step.Parameters) > 0 {
+		keys := make([]string, 0, len(step.Parameters))
+		for key := range step.Parameters {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		collected := make([]string, len(keys))
+		for i, key := range keys {
+			collected[i] = fmt.Sprintf("%s=%v", key, step.Parameters[key])
+		}
+		description.WriteString(" Collected so far: " + strings.Join(collected, ", ") + ".")
+	}
+	if len(step.Missing) > 0 {
+		description.WriteString(" Still needed: " + strings.Join(step.Missing, ", ") + ".")
+	}
+	return description.String()
+}
+
+// truncateToTokens shortens text to about the given number of tokens
+func truncateToTokens(text string, tokens int) string {
+	if EstimateTokens(text) <= tokens {
+		return text
+	}
+	if tokens <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if limit := tokens * 4; limit < len(runes) {
+		runes = runes[:limit]
+	}
+	for len(runes) > 0 && EstimateTokens(string(runes)) > tokens {
+		runes = runes[:len(runes)*9/10]
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+// Template names looked up for a system prompt, most specific first. Names are the
+// paths of the files below the prompt directory without the .tmpl extension.
+const (
+	intentPromptPrefix = "intents/"
+	agentPromptPrefix  = "agents/"
+	systemPromptName   = "system"
+)
+
+// builtinPromptVersion names the prompt set used when the directory cannot be loaded
+const builtinPromptVersion = "builtin"
+
+// PromptData is what prompt templates are rendered with
+type PromptData struct {
+	UserID      string                   // The customer's user ID; an identifier, never a name to greet them by
+	Accounts    []models.Account         // The customer's accounts
+	Intent      string                   // Recognized intent of the current message
+	Entities    map[string]interface{}   // Entities found in the current message
+	Agent       string                   // Agent handling the message
+	PendingStep *models.ConversationStep // Operation the customer is in the middle of, if any
+	ToolResults string                   // Verified results the reply must be grounded in
+	Now         time.Time
+}
+
+// PromptInfo describes the loaded prompt set
+type PromptInfo struct {
+	Version   string    `json:"version"` // From the VERSION file of the directory
+	Hash      string    `json:"hash"`    // Of every template file, so edits without a version bump show
+	Dir       string    `json:"dir"`
+	Templates []string  `json:"templates"`
+	LoadedAt  time.Time `json:"loaded_at"`
+}
+
+// Label identifies the prompt set in logs and conversation messages
+func (i PromptInfo) Label() string {
+	if i.Hash == "" {
+		return i.Version
+	}
+	return i.Version + "@" + i.Hash
+}
+
+// PromptStore holds the system prompt templates, loaded with text/template from
+// *.tmpl files in a directory. A prompt for an intent comes from intents/<intent>.tmpl,
+// else from agents/<agent>.tmpl, else from system.tmpl; every file is parsed into one
+// set, so templates can share definitions made in any of them. Reload swaps in a new
+// set only when all of it parses, so a broken edit never takes the assistant down.
+type PromptStore struct {
+	dir string
+
+	mu        sync.RWMutex
+	templates *template.Template
+	info      PromptInfo
+}
+
+var promptFuncs = template.FuncMap{
+	"join":   strings.Join,
+	"rupees": func(amount float64) string { return fmt.Sprintf("₹%.2f", amount) },
+	"last4": func(number string) string {
+		if len(number) <= 4 {
+			return number
+		}
+		return "****" + number[len(number)-4:]
+	},
+}
+
+// NewPromptStore loads the templates in dir. When they cannot be loaded the built-in
+// system prompt is used until a reload succeeds.
+func NewPromptStore(dir string) *PromptStore {
+	p := &PromptStore{dir: dir}
+	if _, err := p.Reload(); err != nil {
+		log.Printf("[Prompts] Using the built-in system prompt: %v", err)
+		p.templates = template.Must(template.New(systemPromptName).Funcs(promptFuncs).Parse(builtinSystemTemplate))
+		p.info = PromptInfo{
+			Version:   builtinPromptVersion,
+			Templates: []string{systemPromptName},
+			LoadedAt:  time.Now(),
+		}
+	}
+	return p
+}
+
+// Reload parses the templates of the directory again and swaps them in
+func (p *PromptStore) Reload() (PromptInfo, error) {
+	templates, info, err := loadPrompts(p.dir)
+	if err != nil {
+		return PromptInfo{}, err
+	}
+
+	p.mu.Lock()
+	p.templates = templates
+	p.info = info
+	p.mu.Unlock()
+
+	log.Printf("[Prompts] Loaded %d templates from %s, version %s", len(info.Templates), p.dir, info.Label())
+	return info, nil
+}
+
+// Info describes the prompt set in use
+func (p *PromptStore) Info() PromptInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.info
+}
+
+// SystemPrompt renders the most specific template for the intent and agent of data.
+// A template that fails to render falls back to the built-in prompt.
+func (p *PromptStore) SystemPrompt(data PromptData) string {
+	p.mu.RLock()
+	templates := p.templates
+	p.mu.RUnlock()
+
+	var tmpl *template.Template
+	for _, name := range []string{intentPromptPrefix + data.Intent, agentPromptPrefix + data.Agent, systemPromptName} {
+		if tmpl = templates.Lookup(name); tmpl != nil {
+			break
+		}
+	}
+
+	var prompt strings.Builder
+	if tmpl != nil {
+		err := tmpl.Execute(&prompt, data)
+		if err == nil {
+			return strings.TrimSpace(prompt.String())
+		}
+		log.Printf("[Prompts] Template %s failed: %v", tmpl.Name(), err)
+	}
+	return bankingSystemPrompt
+}
+
+// loadPrompts parses every *.tmpl file below dir into one template set
+func loadPrompts(dir string) (*template.Template, PromptInfo, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.HasSuffix(path, ".tmpl") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, PromptInfo{}, err
+	}
+	if len(files) == 0 {
+		return nil, PromptInfo{}, fmt.Errorf("no .tmpl files in %s", dir)
+	}
+	sort.Strings(files)
+
+	templates := template.New("").Funcs(promptFuncs)
+	hash := sha256.New()
+	names := make([]string, len(files))
+	for i, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, PromptInfo{}, err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		names[i] = strings.TrimSuffix(filepath.ToSlash(rel), ".tmpl")
+		if _, err := templates.New(names[i]).Parse(string(content)); err != nil {
+			return nil, PromptInfo{}, fmt.Errorf("%s: %v", rel, err)
+		}
+		fmt.Fprintf(hash, "%s\x00%s\x00", names[i], content)
+	}
+	if templates.Lookup(systemPromptName) == nil {
+		return nil, PromptInfo{}, fmt.Errorf("%s has no %s.tmpl", dir, systemPromptName)
+	}
+
+	version := "unversioned"
+	if content, err := os.ReadFile(filepath.Join(dir, "VERSION")); err == nil && strings.TrimSpace(string(content)) != "" {
+		version = strings.TrimSpace(string(content))
+	}
+
+	return templates, PromptInfo{
+		Version:   version,
+		Hash:      hex.EncodeToString(hash.Sum(nil))[:12],
+		Dir:       dir,
+		Templates: names,
+		LoadedAt:  time.Now(),
+	}, nil
+}
+
+// builtinSystemTemplate is used when no prompt directory can be loaded
+const builtinSystemTemplate = bankingSystemPrompt + `
+{{- if and .Intent (ne .Intent "general")}}
+
+Intent: {{.Intent}}
+{{- if .Entities}}
+Entities:{{range $key, $value := .Entities}} {{$key}}={{$value}}{{end}}
+{{- end}}
+{{- end}}`
@@ -138,7 +138,7 @@ func (s *SessionService) StartCleanupRoutine() {
 		// Clean up expired streaming sessions
 		streamingSessions := s.sessionDAO.GetAllStreamingSessions()
 		for id, session := range streamingSessions {
-			if session.IsExpired() || (session.Done && now.Sub(session.LastPoll) > 5*time.Minute) {
+			if session.IsExpired() || (session.IsDone() && now.Sub(session.IdleSince()) > 5*time.Minute) {
 				s.sessionDAO.DeleteStreamingSession(id)
 				log.Printf("Cleaned up streaming session %s", id)
 			}
@@ -167,7 +167,7 @@ func (s *SessionService) CleanupExpiredSessions() {
 	//now := time.Now()
 	for id, session := range s.sessions {
 		if session.IsExpired() {
-			close(session.ContentChannel) // Close the channel
+			session.MarkDone() // Wake any reader still waiting on it
 			delete(s.sessions, id)
 			log.Printf("Cleaned up expired session: %s", id)
 		}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/banking/ai-agents-banking/src/models"
+)
+
+// tokenPacer passes streamed model text into a streaming session exactly as it
+// arrives. With a rate set it smooths bursts by releasing the text a word at a time,
+// whitespace included, at most that many words per second.
+type tokenPacer struct {
+	session  *models.StreamingSession
+	interval time.Duration // Between released words; 0 passes text straight through
+	pending  []byte        // Start of a UTF-8 sequence whose remaining bytes have not arrived
+	next     time.Time
+}
+
+func newTokenPacer(session *models.StreamingSession, tokensPerSecond float64) *tokenPacer {
+	p := &tokenPacer{session: session}
+	if tokensPerSecond > 0 {
+		p.interval = time.Duration(float64(time.Second) / tokensPerSecond)
+	}
+	return p
+}
+
+// Write releases text into the session. A character split across two writes is
+// held back until it is complete, so the session never holds half a character.
+// Smoothing stops early when ctx ends.
+func (p *tokenPacer) Write(ctx context.Context, text string) {
+	p.pending = append(p.pending, text...)
+	n := completeUTF8(p.pending)
+	if n == 0 {
+		return
+	}
+	chunk := string(p.pending[:n])
+	p.pending = append(p.pending[:0], p.pending[n:]...)
+
+	if p.interval == 0 {
+		p.session.AppendContent(chunk)
+		return
+	}
+	for _, word := range splitWords(chunk) {
+		if !p.wait(ctx) {
+			return
+		}
+		p.session.AppendContent(word)
+	}
+}
+
+// Flush releases whatever is still held back once the model has finished
+func (p *tokenPacer) Flush() {
+	if len(p.pending) > 0 {
+		p.session.AppendContent(strings.ToValidUTF8(string(p.pending), string(utf8.RuneError)))
+		p.pending = nil
+	}
+}
+
+// wait blocks until the next word may be released. It reports false when ctx ends first.
+func (p *tokenPacer) wait(ctx context.Context) bool {
+	now := time.Now()
+	if delay := p.next.Sub(now); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+		}
+		now = p.next
+	}
+	p.next = now.Add(p.interval)
+	return true
+}
+
+// completeUTF8 returns the length of the longest prefix of b that does not end
+// inside a UTF-8 sequence
+func completeUTF8(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:]) {
+				return len(b)
+			}
+			return i
+		}
+	}
+	return len(b)
+}
+
+// splitWords cuts text into words that keep the whitespace following them, so that
+// joined again they are exactly text
+func splitWords(text string) []string {
+	var words []string
+	start := 0
+	inWord, afterSpace := false, false
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			afterSpace = inWord
+			continue
+		}
+		if afterSpace {
+			words = append(words, text[start:i])
+			start = i
+			afterSpace = false
+		}
+		inWord = true
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return words
+}
@@ -1,35 +1,68 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/banking/ai-agents-banking/src/models"
 )
 
 type ToolResult struct {
 	Data      interface{}
 	Timestamp time.Time
 	Error     error
+	UserID    string
+	Tool      string
+	ExpiresAt time.Time
+}
+
+// CachePolicy declares how a tool's results may be reused
+type CachePolicy struct {
+	// ReadOnly tools have no side effects; only their results are ever cached
+	ReadOnly bool
+	// TTL overrides the registry default for a read-only tool
+	TTL time.Duration
+	// Invalidates lists read-only tools whose cached results for the same user
+	// become stale once this tool succeeds
+	Invalidates []string
 }
 
 type ToolRegistry struct {
-	mu       sync.RWMutex
-	tools    map[string]Tool
-	cache    map[string]ToolResult
-	cacheTTL time.Duration
+	mu             sync.RWMutex
+	tools          map[string]Tool
+	cache          map[string]ToolResult
+	cacheTTL       time.Duration
+	defaultTimeout time.Duration
+	timeouts       map[string]time.Duration
 }
 
 type Tool interface {
 	Name() string
 	Description() string
-	Execute(params map[string]interface{}) (interface{}, error)
+	// Parameters declares the accepted parameters; Execute only ever sees values that satisfy it
+	Parameters() ToolSchema
+	CachePolicy() CachePolicy
+	// Execute acts on behalf of principal and must only touch that user's data.
+	// It must give up, without side effects, once ctx is done.
+	Execute(ctx context.Context, principal models.Principal, params map[string]interface{}) (interface{}, error)
 }
 
-func NewToolRegistry(cacheTTL time.Duration) *ToolRegistry {
+// NewToolRegistry creates a registry. Each tool call runs under the timeout listed
+// for it in timeouts, or under defaultTimeout.
+func NewToolRegistry(cacheTTL time.Duration, defaultTimeout time.Duration, timeouts map[string]time.Duration) *ToolRegistry {
 	return &ToolRegistry{
-		tools:    make(map[string]Tool),
-		cache:    make(map[string]ToolResult),
-		cacheTTL: cacheTTL,
+		tools:          make(map[string]Tool),
+		cache:          make(map[string]ToolResult),
+		cacheTTL:       cacheTTL,
+		defaultTimeout: defaultTimeout,
+		timeouts:       timeouts,
 	}
 }
 
@@ -56,53 +89,163 @@ func (tr *ToolRegistry) ListTools() []Tool {
 	return tools
 }
 
-func (tr *ToolRegistry) ExecuteTool(name string, params map[string]interface{}) (interface{}, error) {
-	// Check cache first
-	cacheKey := tr.generateCacheKey(name, params)
-	if result, exists := tr.getCachedResult(cacheKey); exists {
-		return result.Data, result.Error
+// ExecuteTool validates and coerces params against the tool schema before running it
+// on behalf of principal. Parameters that do not fit the schema are reported as a
+// *ValidationError. Results of read-only tools are cached per user; a successful
+// mutating tool evicts the read caches it invalidates instead.
+func (tr *ToolRegistry) ExecuteTool(ctx context.Context, principal models.Principal, name string, params map[string]interface{}) (interface{}, error) {
+	if principal.UserID == "" {
+		return nil, fmt.Errorf("tool %s needs a user to run on behalf of", name)
+	}
+	userID := principal.UserID
+
+	tool, exists := tr.GetTool(name)
+	if !exists {
+		return nil, fmt.Errorf("tool %s not found", name)
+	}
+
+	params, err := tool.Parameters().Coerce(name, params)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := tool.CachePolicy()
+	cacheKey := tr.generateCacheKey(principal, name, params)
+	if policy.ReadOnly {
+		if result, exists := tr.getCachedResult(cacheKey); exists {
+			log.Printf("[ToolRegistry] Cache hit for %s (user %s)", name, userID)
+			return result.Data, nil
+		}
+	}
+
+	timeout := tr.timeoutFor(name)
+	toolCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := toolCtx.Err(); err != nil {
+		return nil, fmt.Errorf("tool %s not started: %w", name, err)
+	}
+
+	result, err := tool.Execute(toolCtx, principal, params)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return nil, fmt.Errorf("tool %s timed out after %v: %w", name, timeout, err)
+		}
+		return nil, err
+	}
+
+	if policy.ReadOnly {
+		ttl := policy.TTL
+		if ttl == 0 {
+			ttl = tr.cacheTTL
+		}
+		tr.cacheResult(cacheKey, userID, name, result, ttl)
+	} else {
+		tr.InvalidateDependents(userID, name)
+	}
+
+	return result, nil
+}
+
+func (tr *ToolRegistry) timeoutFor(name string) time.Duration {
+	if timeout, exists := tr.timeouts[name]; exists && timeout > 0 {
+		return timeout
 	}
+	return tr.defaultTimeout
+}
+
+// IsReadOnly reports whether the named tool has no side effects
+func (tr *ToolRegistry) IsReadOnly(name string) bool {
+	tool, exists := tr.GetTool(name)
+	return exists && tool.CachePolicy().ReadOnly
+}
 
-	// Get and execute tool
+// ValidateParameters coerces params against the tool schema without executing the tool
+func (tr *ToolRegistry) ValidateParameters(name string, params map[string]interface{}) (map[string]interface{}, error) {
 	tool, exists := tr.GetTool(name)
 	if !exists {
 		return nil, fmt.Errorf("tool %s not found", name)
 	}
+	return tool.Parameters().Coerce(name, params)
+}
 
-	result, err := tool.Execute(params)
+// InvalidateDependents evicts the user's cached results of every tool that the
+// named tool's policy invalidates. Call it after a change made outside ExecuteTool.
+func (tr *ToolRegistry) InvalidateDependents(userID string, name string) {
+	tool, exists := tr.GetTool(name)
+	if !exists {
+		return
+	}
+	tr.Invalidate(userID, tool.CachePolicy().Invalidates...)
+}
 
-	// Cache result
-	tr.cacheResult(cacheKey, result, err)
+// Invalidate evicts the user's cached results of the given tools
+func (tr *ToolRegistry) Invalidate(userID string, toolNames ...string) {
+	if len(toolNames) == 0 {
+		return
+	}
+	stale := make(map[string]bool)
+	for _, name := range toolNames {
+		stale[name] = true
+	}
 
-	return result, err
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for key, result := range tr.cache {
+		if result.UserID == userID && stale[result.Tool] {
+			delete(tr.cache, key)
+		}
+	}
+	log.Printf("[ToolRegistry] Invalidated %v for user %s", toolNames, userID)
 }
 
-func (tr *ToolRegistry) generateCacheKey(name string, params map[string]interface{}) string {
-	// Simple cache key generation - can be enhanced based on needs
-	return fmt.Sprintf("%s:%v", name, params)
+// generateCacheKey builds a canonical key: parameters are sorted and each value is
+// tagged with its Go type, so coerced values of the same request always share a key.
+// The selected account is part of the key because tools default to it.
+func (tr *ToolRegistry) generateCacheKey(principal models.Principal, name string, params map[string]interface{}) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	builder.WriteString(principal.UserID + "|" + principal.AccountID + "|" + name)
+	for _, key := range keys {
+		value, err := json.Marshal(params[key])
+		if err != nil {
+			value = []byte(fmt.Sprintf("%v", params[key]))
+		}
+		builder.WriteString(fmt.Sprintf("|%s=%T:%s", key, params[key], value))
+	}
+	return builder.String()
 }
 
 func (tr *ToolRegistry) getCachedResult(key string) (ToolResult, bool) {
 	tr.mu.RLock()
-	defer tr.mu.RUnlock()
 	result, exists := tr.cache[key]
+	tr.mu.RUnlock()
 	if !exists {
 		return ToolResult{}, false
 	}
-	if time.Since(result.Timestamp) > tr.cacheTTL {
+	if time.Now().After(result.ExpiresAt) {
+		tr.mu.Lock()
 		delete(tr.cache, key)
+		tr.mu.Unlock()
 		return ToolResult{}, false
 	}
 	return result, true
 }
 
-func (tr *ToolRegistry) cacheResult(key string, data interface{}, err error) {
+func (tr *ToolRegistry) cacheResult(key string, userID string, name string, data interface{}, ttl time.Duration) {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
+	now := time.Now()
 	tr.cache[key] = ToolResult{
 		Data:      data,
-		Timestamp: time.Now(),
-		Error:     err,
+		Timestamp: now,
+		UserID:    userID,
+		Tool:      name,
+		ExpiresAt: now.Add(ttl),
 	}
 }
 
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Parameter types understood by ToolSchema, named as in JSON Schema
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+)
+
+// Reasons reported in a FieldError
+const (
+	ReasonMissing     = "missing"
+	ReasonInvalidType = "invalid_type"
+	ReasonNotAllowed  = "not_allowed"
+	ReasonOutOfRange  = "out_of_range"
+)
+
+// ParameterSchema describes a single tool parameter
+type ParameterSchema struct {
+	Type             string      `json:"type"`
+	Description      string      `json:"description,omitempty"`
+	Enum             []string    `json:"enum,omitempty"`
+	Minimum          *float64    `json:"minimum,omitempty"`
+	ExclusiveMinimum *float64    `json:"exclusiveMinimum,omitempty"`
+	Default          interface{} `json:"default,omitempty"`
+}
+
+// ToolSchema is the JSON-Schema-style object declaration of a tool's parameters
+type ToolSchema struct {
+	Type       string                     `json:"type"`
+	Properties map[string]ParameterSchema `json:"properties"`
+	Required   []string                   `json:"required,omitempty"`
+}
+
+// NewToolSchema declares an object schema with the given properties and required names
+func NewToolSchema(properties map[string]ParameterSchema, required ...string) ToolSchema {
+	return ToolSchema{
+		Type:       "object",
+		Properties: properties,
+		Required:   required,
+	}
+}
+
+// FieldError explains why a single parameter was rejected
+type FieldError struct {
+	Parameter string      `json:"parameter"`
+	Reason    string      `json:"reason"`
+	Value     interface{} `json:"value,omitempty"`
+	Message   string      `json:"message"`
+}
+
+// ValidationError is returned by ToolRegistry.ExecuteTool when parameters do not
+// match the tool schema. Fields are ordered so the first one is the best follow-up question.
+type ValidationError struct {
+	Tool   string       `json:"tool"`
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return fmt.Sprintf("invalid parameters for %s: %s", e.Tool, strings.Join(messages, "; "))
+}
+
+// Parameters returns the names of the rejected parameters
+func (e *ValidationError) Parameters() []string {
+	names := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		names[i] = field.Parameter
+	}
+	return names
+}
+
+// Coerce validates params against the schema and returns a copy in which every
+// declared parameter has its declared Go type (string, float64, int or bool),
+// enum values use their canonical spelling and defaults are filled in.
+// Undeclared parameters are passed through unchanged.
+func (s ToolSchema) Coerce(tool string, params map[string]interface{}) (map[string]interface{}, error) {
+	coerced := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		coerced[key] = value
+	}
+
+	required := make(map[string]bool)
+	for _, name := range s.Required {
+		required[name] = true
+	}
+
+	var fields []FieldError
+	for _, name := range s.orderedProperties() {
+		schema := s.Properties[name]
+		value, exists := params[name]
+		if exists && isBlank(value) {
+			exists = false
+			delete(coerced, name)
+		}
+
+		if !exists {
+			if schema.Default != nil {
+				coerced[name] = schema.Default
+			} else if required[name] {
+				fields = append(fields, FieldError{
+					Parameter: name,
+					Reason:    ReasonMissing,
+					Message:   fmt.Sprintf("%s is required", name),
+				})
+			}
+			continue
+		}
+
+		converted, fieldErr := schema.coerce(name, value)
+		if fieldErr != nil {
+			fields = append(fields, *fieldErr)
+			continue
+		}
+		coerced[name] = converted
+	}
+
+	if len(fields) > 0 {
+		return nil, &ValidationError{Tool: tool, Fields: fields}
+	}
+	return coerced, nil
+}
+
+// orderedProperties lists required parameters first, in declaration order, then the rest alphabetically
+func (s ToolSchema) orderedProperties() []string {
+	names := make([]string, 0, len(s.Properties))
+	seen := make(map[string]bool)
+	for _, name := range s.Required {
+		if _, exists := s.Properties[name]; exists && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	var optional []string
+	for name := range s.Properties {
+		if !seen[name] {
+			optional = append(optional, name)
+		}
+	}
+	sort.Strings(optional)
+	return append(names, optional...)
+}
+
+func (p ParameterSchema) coerce(name string, value interface{}) (interface{}, *FieldError) {
+	invalid := func(reason, message string) *FieldError {
+		return &FieldError{Parameter: name, Reason: reason, Value: value, Message: message}
+	}
+
+	var converted interface{}
+	switch p.Type {
+	case TypeNumber:
+		number, ok := toFloat(value)
+		if !ok {
+			return nil, invalid(ReasonInvalidType, fmt.Sprintf("%s must be a number", name))
+		}
+		converted = number
+	case TypeInteger:
+		number, ok := toFloat(value)
+		if !ok || number != math.Trunc(number) {
+			return nil, invalid(ReasonInvalidType, fmt.Sprintf("%s must be a whole number", name))
+		}
+		converted = int(number)
+	case TypeBoolean:
+		switch v := value.(type) {
+		case bool:
+			converted = v
+		default:
+			b, err := strconv.ParseBool(strings.TrimSpace(fmt.Sprintf("%v", v)))
+			if err != nil {
+				return nil, invalid(ReasonInvalidType, fmt.Sprintf("%s must be true or false", name))
+			}
+			converted = b
+		}
+	default:
+		converted = strings.TrimSpace(fmt.Sprintf("%v", value))
+	}
+
+	if len(p.Enum) > 0 {
+		canonical, ok := matchEnum(p.Enum, fmt.Sprintf("%v", converted))
+		if !ok {
+			return nil, invalid(ReasonNotAllowed, fmt.Sprintf("%s must be one of %s", name, strings.Join(p.Enum, ", ")))
+		}
+		converted = canonical
+	}
+
+	if number, ok := toFloat(converted); ok && p.Type != TypeString {
+		if p.Minimum != nil && number < *p.Minimum {
+			return nil, invalid(ReasonOutOfRange, fmt.Sprintf("%s must be at least %v", name, *p.Minimum))
+		}
+		if p.ExclusiveMinimum != nil && number <= *p.ExclusiveMinimum {
+			return nil, invalid(ReasonOutOfRange, fmt.Sprintf("%s must be greater than %v", name, *p.ExclusiveMinimum))
+		}
+	}
+
+	return converted, nil
+}
+
+// toFloat accepts Go numbers and numeric strings such as "5000", "5,000" or "₹ 5000.50".
+// NaN and infinities are not numbers a tool can act on and are rejected.
+func toFloat(value interface{}) (float64, bool) {
+	number, ok := parseFloat(value)
+	if !ok || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, false
+	}
+	return number, true
+}
+
+func parseFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case string:
+		cleaned := strings.ToLower(strings.TrimSpace(v))
+		cleaned = strings.TrimPrefix(cleaned, "₹")
+		cleaned = strings.TrimPrefix(cleaned, "rs.")
+		cleaned = strings.TrimPrefix(cleaned, "rs")
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+		number, err := strconv.ParseFloat(strings.TrimSpace(cleaned), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+func matchEnum(allowed []string, value string) (string, bool) {
+	for _, option := range allowed {
+		if strings.EqualFold(option, strings.TrimSpace(value)) {
+			return option, true
+		}
+	}
+	return "", false
+}
+
+func isBlank(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
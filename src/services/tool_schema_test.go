@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestToolSchemaCoerce(t *testing.T) {
+	schema := NewToolSchema(map[string]ParameterSchema{
+		"amount":       {Type: TypeNumber, ExclusiveMinimum: floatPtr(0)},
+		"tenure":       {Type: TypeInteger, Minimum: floatPtr(1)},
+		"account_type": {Type: TypeString, Enum: []string{"savings", "current"}, Default: "savings"},
+		"notify":       {Type: TypeBoolean},
+		"to_account":   {Type: TypeString},
+	}, "to_account", "amount")
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   map[string]interface{}
+		errors map[string]string // Reason by rejected parameter
+	}{
+		{
+			name:   "numbers from strings",
+			params: map[string]interface{}{"to_account": " ACC_002 ", "amount": "₹ 1,500.50", "tenure": "12", "notify": "true"},
+			want:   map[string]interface{}{"to_account": "ACC_002", "amount": 1500.5, "tenure": 12, "notify": true, "account_type": "savings"},
+		},
+		{
+			name:   "enum canonical spelling and undeclared passed through",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": 10, "account_type": "Current", "memo": "rent"},
+			want:   map[string]interface{}{"to_account": "ACC_002", "amount": 10.0, "account_type": "current", "memo": "rent"},
+		},
+		{
+			name:   "missing and blank required",
+			params: map[string]interface{}{"to_account": "  "},
+			errors: map[string]string{"to_account": ReasonMissing, "amount": ReasonMissing},
+		},
+		{
+			name:   "zero is not above the exclusive minimum",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": 0},
+			errors: map[string]string{"amount": ReasonOutOfRange},
+		},
+		{
+			name:   "below the minimum",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": 1, "tenure": 0},
+			errors: map[string]string{"tenure": ReasonOutOfRange},
+		},
+		{
+			name:   "NaN",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": math.NaN()},
+			errors: map[string]string{"amount": ReasonInvalidType},
+		},
+		{
+			name:   "NaN as a string",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": "NaN"},
+			errors: map[string]string{"amount": ReasonInvalidType},
+		},
+		{
+			name:   "infinity",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": math.Inf(1)},
+			errors: map[string]string{"amount": ReasonInvalidType},
+		},
+		{
+			name:   "infinity as a string",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": "Infinity"},
+			errors: map[string]string{"amount": ReasonInvalidType},
+		},
+		{
+			name:   "infinite whole number",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": 1, "tenure": "-Inf"},
+			errors: map[string]string{"tenure": ReasonInvalidType},
+		},
+		{
+			name:   "fractional whole number",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": 1, "tenure": 1.5},
+			errors: map[string]string{"tenure": ReasonInvalidType},
+		},
+		{
+			name:   "not a boolean",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": 1, "notify": "maybe"},
+			errors: map[string]string{"notify": ReasonInvalidType},
+		},
+		{
+			name:   "not an enum value",
+			params: map[string]interface{}{"to_account": "ACC_002", "amount": 1, "account_type": "salary"},
+			errors: map[string]string{"account_type": ReasonNotAllowed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.Coerce("transfer_funds", tt.params)
+			if tt.errors == nil {
+				if err != nil {
+					t.Fatalf("Coerce() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Coerce() = %v; want %v", got, tt.want)
+				}
+				return
+			}
+
+			var validation *ValidationError
+			if !errors.As(err, &validation) {
+				t.Fatalf("Coerce() error = %v; want a ValidationError", err)
+			}
+			reasons := make(map[string]string)
+			for _, field := range validation.Fields {
+				reasons[field.Parameter] = field.Reason
+			}
+			if !reflect.DeepEqual(reasons, tt.errors) {
+				t.Errorf("Coerce() rejected %v; want %v", reasons, tt.errors)
+			}
+		})
+	}
+}
+
+func TestValidationErrorOrder(t *testing.T) {
+	schema := NewToolSchema(map[string]ParameterSchema{
+		"b":      {Type: TypeNumber},
+		"a":      {Type: TypeNumber},
+		"amount": {Type: TypeNumber},
+		"payee":  {Type: TypeString},
+	}, "payee", "amount")
+
+	_, err := schema.Coerce("tool", map[string]interface{}{"a": "x", "b": "y"})
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Coerce() error = %v; want a ValidationError", err)
+	}
+	// Required parameters first, in declaration order, then the rest alphabetically
+	want := []string{"payee", "amount", "a", "b"}
+	if got := validation.Parameters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parameters() = %v; want %v", got, want)
+	}
+}